@@ -0,0 +1,42 @@
+package aliyun
+
+import (
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	openapiutil "github.com/alibabacloud-go/openapi-util/service"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	tea "github.com/alibabacloud-go/tea/tea"
+)
+
+/* Call aliyun RPC style API without product SDK, return the response body */
+func _callApi(
+	accessConfig *AccessConfig,
+	endpoint string,
+	version string,
+	action string,
+	query map[string]interface{},
+) (map[string]interface{}, error) {
+	client, err := openapi.NewClient(_getClientConfig(accessConfig, endpoint))
+	if err != nil {
+		return nil, err
+	}
+	params := &openapi.Params{
+		Action:      tea.String(action),
+		Version:     tea.String(version),
+		Protocol:    tea.String("HTTPS"),
+		Pathname:    tea.String("/"),
+		Method:      tea.String("POST"),
+		AuthType:    tea.String("AK"),
+		Style:       tea.String("RPC"),
+		ReqBodyType: tea.String("formData"),
+		BodyType:    tea.String("json"),
+	}
+	request := &openapi.OpenApiRequest{
+		Query: openapiutil.Query(query),
+	}
+	response, err := client.CallApi(params, request, &util.RuntimeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	body, _ := response["body"].(map[string]interface{})
+	return body, nil
+}
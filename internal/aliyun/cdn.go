@@ -0,0 +1,504 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+const (
+	_CDN_ENDPOINT string = "cdn.aliyuncs.com"
+	_CDN_VERSION  string = "2018-05-10"
+)
+
+const (
+	CDN_FUNCTION_FILETYPE_TTL string = "filetype_based_ttl_set"
+	CDN_FUNCTION_PATH_TTL     string = "path_based_ttl_set"
+	CDN_FUNCTION_RESP_HEADER  string = "set_resp_header"
+	CDN_FUNCTION_LIMIT_RATE   string = "limit_rate"
+	CDN_FUNCTION_CONDITION    string = "condition"
+)
+
+// 规则引擎条件名称前缀，区分ezfaas管理的条件
+const _CDN_CONDITION_PREFIX string = "ezfaas-"
+
+type CDNCacheConfigParams struct {
+	Domain     string
+	UsageLimit string
+	RulesFile  string // 为空表示使用默认规则
+	// 覆盖规则文件中的用量封顶配置，零值表示不覆盖
+	UsageLimitOverride common.CDNUsageLimit
+	Yes                bool
+}
+
+type CDNFunctionArg struct {
+	ArgName  string `json:"argName"`
+	ArgValue string `json:"argValue"`
+}
+
+type CDNFunction struct {
+	FunctionName string           `json:"functionName"`
+	FunctionArgs []CDNFunctionArg `json:"functionArgs"`
+	ParentId     string           `json:"parentId,omitempty"` // 规则引擎条件ID
+	Condition    string           `json:"-"`                  // 规则引擎条件名称，条件创建后才有ID
+}
+
+func _newCDNFunction(name string, args ...string) CDNFunction {
+	function := CDNFunction{FunctionName: name}
+	for i := 0; i+1 < len(args); i += 2 {
+		function.FunctionArgs = append(function.FunctionArgs, CDNFunctionArg{
+			ArgName:  args[i],
+			ArgValue: args[i+1],
+		})
+	}
+	return function
+}
+
+func (f CDNFunction) String() string {
+	var args []string
+	for _, arg := range f.FunctionArgs {
+		args = append(args, fmt.Sprintf("%s=%s", arg.ArgName, arg.ArgValue))
+	}
+	if f.Condition != "" {
+		args = append(args, "if="+f.Condition)
+	}
+	return fmt.Sprintf("%s %s", f.FunctionName, strings.Join(args, " "))
+}
+
+/*
+Convert cache rules to aliyun ttl functions. Later rules have higher weight,
+the same as rule priority of tencent. Index rule is not supported by aliyun,
+follow_origin is the default behavior of aliyun when no rule matched.
+Path of aliyun matches by directory prefix, so path rule such as
+/manifest.json also matches /manifest.json/ and everything under it.
+*/
+func getCacheFunctions(rules []common.CDNCacheRule) []CDNFunction {
+	var functions []CDNFunction
+	for i, rule := range rules {
+		if rule.FollowOrigin {
+			continue
+		}
+		ttl := strconv.FormatInt(rule.TTL, 10)
+		if rule.NoCache {
+			ttl = "0"
+		}
+		weight := strconv.Itoa(i + 1)
+		switch rule.Type {
+		case common.CDN_RULE_ALL:
+			functions = append(functions, _newCDNFunction(
+				CDN_FUNCTION_PATH_TTL, "path", "/", "ttl", ttl, "weight", weight))
+		case common.CDN_RULE_DIRECTORY, common.CDN_RULE_PATH:
+			for _, path := range rule.Paths {
+				functions = append(functions, _newCDNFunction(
+					CDN_FUNCTION_PATH_TTL, "path", path, "ttl", ttl, "weight", weight))
+			}
+		case common.CDN_RULE_FILE:
+			functions = append(functions, _newCDNFunction(
+				CDN_FUNCTION_FILETYPE_TTL, "file_type", strings.Join(rule.Paths, ","),
+				"ttl", ttl, "weight", weight))
+		default:
+			log.Printf("[WARN] Aliyun CDN not support %s cache rule, skipped", rule.Type)
+		}
+	}
+	return functions
+}
+
+/* Regular expression of uri matched by max age rule */
+func _getMaxAgeRuleRegex(rule common.CDNMaxAgeRule) string {
+	var patterns []string
+	for _, path := range rule.Paths {
+		switch rule.Type {
+		case common.CDN_RULE_ALL:
+			patterns = append(patterns, "^/")
+		case common.CDN_RULE_INDEX:
+			patterns = append(patterns, "^/$")
+		case common.CDN_RULE_DIRECTORY:
+			patterns = append(patterns, "^"+regexp.QuoteMeta(strings.TrimSuffix(path, "/"))+"(/|$)")
+		case common.CDN_RULE_PATH:
+			patterns = append(patterns, "^"+regexp.QuoteMeta(path)+"$")
+		case common.CDN_RULE_FILE:
+			patterns = append(patterns, `\.`+regexp.QuoteMeta(path)+"$")
+		}
+	}
+	return strings.Join(patterns, "|")
+}
+
+func _newCDNCondition(name string, criteria []map[string]interface{}) CDNFunction {
+	rule := map[string]interface{}{
+		"name":   name,
+		"status": "enable",
+		"match": map[string]interface{}{
+			"logic":    "and",
+			"criteria": criteria,
+		},
+	}
+	data, _ := json.Marshal(rule)
+	return _newCDNFunction(CDN_FUNCTION_CONDITION, "rule", string(data))
+}
+
+func _newCDNUriCriteria(regex string, negate bool) map[string]interface{} {
+	return map[string]interface{}{
+		"matchType":     "uri",
+		"matchOperator": "regex",
+		"matchValue":    regex,
+		"negate":        negate,
+	}
+}
+
+/*
+Browser cache is set by Cache-Control header. Header of each rule is set
+under a rule engine condition which matches paths of the rule but not paths
+of later rules, so later rules have higher priority the same as tencent.
+*/
+func getMaxAgeFunctions(rules []common.CDNMaxAgeRule) []CDNFunction {
+	var functions []CDNFunction
+	for i, rule := range rules {
+		if rule.FollowOrigin {
+			continue
+		}
+		header := _newCDNFunction(
+			CDN_FUNCTION_RESP_HEADER,
+			"key", "Cache-Control",
+			"value", fmt.Sprintf("max-age=%d", rule.TTL),
+		)
+		var criteria []map[string]interface{}
+		if rule.Type != common.CDN_RULE_ALL {
+			criteria = append(criteria, _newCDNUriCriteria(_getMaxAgeRuleRegex(rule), false))
+		}
+		for _, laterRule := range rules[i+1:] {
+			criteria = append(criteria, _newCDNUriCriteria(_getMaxAgeRuleRegex(laterRule), true))
+		}
+		if len(criteria) > 0 {
+			header.Condition = fmt.Sprintf("%smax-age-%d", _CDN_CONDITION_PREFIX, i)
+			functions = append(functions, _newCDNCondition(header.Condition, criteria))
+		}
+		functions = append(functions, header)
+	}
+	return functions
+}
+
+/* Only downstream speed limit is supported, ip qps and bandwidth cap are set in aliyun console */
+func getUsageLimitFunctions(limit common.CDNUsageLimit) []CDNFunction {
+	log.Printf("[WARN] Aliyun CDN ip_qps and bandwidth_mbps are not supported, skipped")
+	return []CDNFunction{_newCDNFunction(
+		CDN_FUNCTION_LIMIT_RATE,
+		"ali_limit_start_hour", "0",
+		"ali_limit_end_hour", "24",
+		"traffic_limit_unit", "k",
+		"traffic_limit_arg", strconv.FormatInt(limit.DownstreamKBps, 10),
+	)}
+}
+
+type CDNDomainConfig struct {
+	ConfigId     string
+	ParentId     string
+	FunctionName string
+	FunctionArgs struct {
+		FunctionArg []struct {
+			ArgName  string
+			ArgValue string
+		}
+	}
+}
+
+func (c CDNDomainConfig) GetArg(name string) string {
+	for _, arg := range c.FunctionArgs.FunctionArg {
+		if arg.ArgName == name {
+			return arg.ArgValue
+		}
+	}
+	return ""
+}
+
+/* Name of rule engine condition, empty if not a condition */
+func (c CDNDomainConfig) GetConditionName() string {
+	if c.FunctionName != CDN_FUNCTION_CONDITION {
+		return ""
+	}
+	var rule struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal([]byte(c.GetArg("rule")), &rule) != nil {
+		return ""
+	}
+	return rule.Name
+}
+
+func _describeCDNConfigs(
+	accessConfig *AccessConfig,
+	domain string,
+	functionNames []string,
+) ([]CDNDomainConfig, error) {
+	body, err := _callApi(accessConfig, _CDN_ENDPOINT, _CDN_VERSION, "DescribeCdnDomainConfigs",
+		map[string]interface{}{
+			"DomainName":    domain,
+			"FunctionNames": strings.Join(functionNames, ","),
+		})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		DomainConfigs struct {
+			DomainConfig []CDNDomainConfig
+		}
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.DomainConfigs.DomainConfig, nil
+}
+
+/*
+Get existed configs of functions, response headers other than Cache-Control
+and rule engine conditions not created by ezfaas are not managed.
+*/
+func _getManagedCDNConfigs(
+	accessConfig *AccessConfig,
+	domain string,
+	functionNames []string,
+) ([]CDNDomainConfig, error) {
+	configs, err := _describeCDNConfigs(accessConfig, domain, functionNames)
+	if err != nil {
+		return nil, err
+	}
+	return _filterManagedCDNConfigs(configs), nil
+}
+
+func _filterManagedCDNConfigs(configs []CDNDomainConfig) []CDNDomainConfig {
+	conditionIds := map[string]bool{}
+	for _, config := range configs {
+		if strings.HasPrefix(config.GetConditionName(), _CDN_CONDITION_PREFIX) {
+			conditionIds[config.ConfigId] = true
+		}
+	}
+	var result []CDNDomainConfig
+	for _, config := range configs {
+		if config.FunctionName == CDN_FUNCTION_CONDITION && !conditionIds[config.ConfigId] {
+			continue
+		}
+		if config.FunctionName == CDN_FUNCTION_RESP_HEADER &&
+			!strings.EqualFold(config.GetArg("key"), "Cache-Control") {
+			continue
+		}
+		if config.ParentId != "" && !conditionIds[config.ParentId] {
+			continue
+		}
+		result = append(result, config)
+	}
+	return result
+}
+
+/*
+Set ParentId of functions to id of existed condition, functions whose
+condition not existed yet are kept without ParentId.
+*/
+func _resolveCDNConditions(configs []CDNDomainConfig, functions []CDNFunction) []CDNFunction {
+	conditionIds := map[string]string{}
+	for _, function := range functions {
+		if function.FunctionName != CDN_FUNCTION_CONDITION {
+			continue
+		}
+		for _, config := range configs {
+			if _isCDNConfigOf(config, function) {
+				conditionIds[config.GetConditionName()] = config.ConfigId
+				break
+			}
+		}
+	}
+	result := make([]CDNFunction, len(functions))
+	for i, function := range functions {
+		if function.Condition != "" {
+			function.ParentId = conditionIds[function.Condition]
+		}
+		result[i] = function
+	}
+	return result
+}
+
+/* Function is ready to set if its condition existed */
+func _isCDNFunctionReady(function CDNFunction) bool {
+	return function.Condition == "" || function.ParentId != ""
+}
+
+/*
+Config matches function if all args of function are the same, rule of
+condition is compared as JSON.
+*/
+func _isCDNConfigOf(config CDNDomainConfig, function CDNFunction) bool {
+	if config.FunctionName != function.FunctionName || config.ParentId != function.ParentId {
+		return false
+	}
+	for _, arg := range function.FunctionArgs {
+		value := config.GetArg(arg.ArgName)
+		if function.FunctionName == CDN_FUNCTION_CONDITION && arg.ArgName == "rule" {
+			if !common.IsJSONSubset(arg.ArgValue, value) {
+				return false
+			}
+		} else if value != arg.ArgValue {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Diff existed configs and declared functions, return functions to set and
+ids of stale configs to delete. Matched configs are kept unchanged.
+*/
+func _diffCDNConfigs(
+	configs []CDNDomainConfig,
+	functions []CDNFunction,
+) ([]CDNFunction, []string) {
+	matched := map[int]bool{}
+	var newFunctions []CDNFunction
+	for _, function := range functions {
+		isMatched := false
+		for i, config := range configs {
+			if !matched[i] && _isCDNConfigOf(config, function) {
+				matched[i] = true
+				isMatched = true
+				break
+			}
+		}
+		if !isMatched {
+			newFunctions = append(newFunctions, function)
+		}
+	}
+	var staleIds []string
+	for i, config := range configs {
+		if !matched[i] {
+			staleIds = append(staleIds, config.ConfigId)
+		}
+	}
+	return newFunctions, staleIds
+}
+
+func _deleteCDNConfigs(accessConfig *AccessConfig, domain string, configIds []string) error {
+	log.Printf("[INFO] Delete CDN configs %s", strings.Join(configIds, ","))
+	_, err := _callApi(accessConfig, _CDN_ENDPOINT, _CDN_VERSION, "DeleteSpecificConfig",
+		map[string]interface{}{
+			"DomainName": domain,
+			"ConfigId":   strings.Join(configIds, ","),
+		})
+	return err
+}
+
+/*
+Update CDN cache config declaratively. New configs are set before stale
+configs are deleted, so the domain always has cache rules if set failed.
+*/
+func UpdateCDNCacheConfig(params CDNCacheConfigParams) (map[string]interface{}, error) {
+	rules, err := common.LoadCDNRules(params.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	if params.RulesFile != "" {
+		log.Printf("[INFO] CDN rules=%s", params.RulesFile)
+	}
+	common.MergeCDNUsageLimit(&rules.UsageLimit, params.UsageLimitOverride)
+	err = common.ValidateCDNRules(*rules)
+	if err != nil {
+		return nil, err
+	}
+	if rules.Http2 != nil || rules.Referer != nil || rules.IpFilter != nil ||
+		rules.ResponseHeader != nil || rules.Compression != nil {
+		log.Printf("[WARN] Aliyun CDN access control and header rules are not supported, skipped")
+	}
+	// --usagelimit参数优先
+	usageLimit := strings.ToLower(params.UsageLimit)
+	if usageLimit == "on" || usageLimit == "off" {
+		isEnable := usageLimit == "on"
+		rules.UsageLimit.Enabled = &isEnable
+	}
+	managedFunctions := []string{
+		CDN_FUNCTION_FILETYPE_TTL, CDN_FUNCTION_PATH_TTL, CDN_FUNCTION_RESP_HEADER,
+		CDN_FUNCTION_CONDITION,
+	}
+	functions := getCacheFunctions(rules.Cache)
+	functions = append(functions, getMaxAgeFunctions(rules.MaxAge)...)
+	if rules.UsageLimit.Enabled != nil {
+		managedFunctions = append(managedFunctions, CDN_FUNCTION_LIMIT_RATE)
+		if *rules.UsageLimit.Enabled {
+			functions = append(functions, getUsageLimitFunctions(rules.UsageLimit)...)
+		}
+	}
+	accessConfig, err := LoadAccessConfig()
+	if err != nil {
+		return nil, err
+	}
+	configs, err := _getManagedCDNConfigs(accessConfig, params.Domain, managedFunctions)
+	if err != nil {
+		return nil, err
+	}
+	newFunctions, staleIds := _diffCDNConfigs(configs, _resolveCDNConditions(configs, functions))
+	if len(newFunctions) <= 0 && len(staleIds) <= 0 {
+		log.Printf("[INFO] CDN config of %s has no changes", params.Domain)
+		return nil, nil
+	}
+	var lines []string
+	for _, function := range functions {
+		lines = append(lines, "  "+function.String())
+	}
+	log.Printf("[INFO] CDN config of %s, replace %s:\n%s", params.Domain,
+		strings.Join(managedFunctions, ","), strings.Join(lines, "\n"))
+	if !params.Yes {
+		if !common.Comfirm("Confirm Update CDN Config") {
+			return nil, common.ErrCanceled
+		}
+	}
+	var output map[string]interface{}
+	// 规则引擎条件创建后才能设置依赖它的配置，最多分两次设置
+	for round := 0; round < 2 && len(newFunctions) > 0; round++ {
+		var readyFunctions []CDNFunction
+		for _, function := range newFunctions {
+			if _isCDNFunctionReady(function) {
+				readyFunctions = append(readyFunctions, function)
+			}
+		}
+		if len(readyFunctions) <= 0 {
+			break
+		}
+		functionsJSON, err := json.Marshal(readyFunctions)
+		if err != nil {
+			return nil, err
+		}
+		output, err = _callApi(accessConfig, _CDN_ENDPOINT, _CDN_VERSION, "BatchSetCdnDomainConfig",
+			map[string]interface{}{
+				"DomainNames": params.Domain,
+				"Functions":   string(functionsJSON),
+			})
+		if err != nil {
+			return nil, err
+		}
+		// 同名配置可能被原地更新，重新对比后再删除
+		configs, err = _getManagedCDNConfigs(accessConfig, params.Domain, managedFunctions)
+		if err != nil {
+			return nil, err
+		}
+		newFunctions, staleIds = _diffCDNConfigs(configs, _resolveCDNConditions(configs, functions))
+	}
+	if len(newFunctions) > 0 {
+		var names []string
+		for _, function := range newFunctions {
+			names = append(names, function.String())
+		}
+		return nil, fmt.Errorf("CDN configs not applied: %s", strings.Join(names, "; "))
+	}
+	if len(staleIds) > 0 {
+		err = _deleteCDNConfigs(accessConfig, params.Domain, staleIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,176 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+func _testCDNConfig(id string, function CDNFunction) CDNDomainConfig {
+	config := CDNDomainConfig{ConfigId: id, ParentId: function.ParentId, FunctionName: function.FunctionName}
+	for _, arg := range function.FunctionArgs {
+		config.FunctionArgs.FunctionArg = append(config.FunctionArgs.FunctionArg, struct {
+			ArgName  string
+			ArgValue string
+		}{arg.ArgName, arg.ArgValue})
+	}
+	return config
+}
+
+func TestDiffCDNConfigs(t *testing.T) {
+	root := _newCDNFunction(CDN_FUNCTION_PATH_TTL, "path", "/", "ttl", "60", "weight", "1")
+	rootNew := _newCDNFunction(CDN_FUNCTION_PATH_TTL, "path", "/", "ttl", "120", "weight", "1")
+	static := _newCDNFunction(CDN_FUNCTION_PATH_TTL, "path", "/static/", "ttl", "3600", "weight", "2")
+	maxAge := _newCDNFunction(CDN_FUNCTION_RESP_HEADER, "key", "Cache-Control", "value", "max-age=60")
+	cases := []struct {
+		name         string
+		configs      []CDNDomainConfig
+		functions    []CDNFunction
+		newFunctions []CDNFunction
+		staleIds     []string
+	}{
+		{
+			name:         "no changes",
+			configs:      []CDNDomainConfig{_testCDNConfig("1", root), _testCDNConfig("2", maxAge)},
+			functions:    []CDNFunction{root, maxAge},
+			newFunctions: nil,
+			staleIds:     nil,
+		},
+		{
+			name:         "changed ttl",
+			configs:      []CDNDomainConfig{_testCDNConfig("1", root), _testCDNConfig("2", static)},
+			functions:    []CDNFunction{rootNew, static},
+			newFunctions: []CDNFunction{rootNew},
+			staleIds:     []string{"1"},
+		},
+		{
+			name:         "duplicated config",
+			configs:      []CDNDomainConfig{_testCDNConfig("1", root), _testCDNConfig("2", root)},
+			functions:    []CDNFunction{root},
+			newFunctions: nil,
+			staleIds:     []string{"2"},
+		},
+		{
+			name:         "delete all",
+			configs:      []CDNDomainConfig{_testCDNConfig("1", root)},
+			functions:    nil,
+			newFunctions: nil,
+			staleIds:     []string{"1"},
+		},
+	}
+	for _, c := range cases {
+		newFunctions, staleIds := _diffCDNConfigs(c.configs, c.functions)
+		if !reflect.DeepEqual(newFunctions, c.newFunctions) {
+			t.Errorf("%s: new functions = %v, expect %v", c.name, newFunctions, c.newFunctions)
+		}
+		if !reflect.DeepEqual(staleIds, c.staleIds) {
+			t.Errorf("%s: stale ids = %v, expect %v", c.name, staleIds, c.staleIds)
+		}
+	}
+}
+
+/* Match uri by condition rule, the same as aliyun rule engine with and logic */
+func _matchCDNCondition(t *testing.T, condition CDNFunction, uri string) bool {
+	var rule struct {
+		Match struct {
+			Criteria []struct {
+				MatchValue string `json:"matchValue"`
+				Negate     bool   `json:"negate"`
+			} `json:"criteria"`
+		} `json:"match"`
+	}
+	if err := json.Unmarshal([]byte(condition.FunctionArgs[0].ArgValue), &rule); err != nil {
+		t.Fatal(err)
+	}
+	for _, criteria := range rule.Match.Criteria {
+		if regexp.MustCompile(criteria.MatchValue).MatchString(uri) == criteria.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetMaxAgeFunctions(t *testing.T) {
+	functions := getMaxAgeFunctions(common.DefaultCDNRules().MaxAge)
+	conditions := map[string]CDNFunction{}
+	var headers []CDNFunction
+	for _, function := range functions {
+		if function.FunctionName == CDN_FUNCTION_CONDITION {
+			var rule struct{ Name string }
+			json.Unmarshal([]byte(function.FunctionArgs[0].ArgValue), &rule)
+			conditions[rule.Name] = function
+		} else {
+			headers = append(headers, function)
+		}
+	}
+	// 后面的规则优先，和腾讯云一致
+	cases := []struct {
+		uri    string
+		maxAge string // 为空表示跟随源站
+	}{
+		{"/", "max-age=30"},
+		{"/about", ""},
+		{"/api/users", ""},
+		{"/static/app.js", "max-age=864000"},
+		{"/js", "max-age=864000"},
+		{"/jsx/app.js", ""},
+		{"/favicon.ico", "max-age=864000"},
+		{"/manifest.json", "max-age=30"},
+	}
+	for _, c := range cases {
+		var matched []string
+		for _, header := range headers {
+			condition, ok := conditions[header.Condition]
+			if !ok {
+				t.Fatalf("condition %s not found", header.Condition)
+			}
+			if _matchCDNCondition(t, condition, c.uri) {
+				matched = append(matched, header.FunctionArgs[1].ArgValue)
+			}
+		}
+		var expect []string
+		if c.maxAge != "" {
+			expect = []string{c.maxAge}
+		}
+		if !reflect.DeepEqual(matched, expect) {
+			t.Errorf("%s: max age = %v, expect %v", c.uri, matched, expect)
+		}
+	}
+	// 最后的全部规则不需要条件
+	rules := []common.CDNMaxAgeRule{{Type: common.CDN_RULE_ALL, Paths: []string{"*"}, TTL: 60}}
+	functions = getMaxAgeFunctions(rules)
+	if len(functions) != 1 || functions[0].Condition != "" {
+		t.Errorf("all rule functions = %v", functions)
+	}
+}
+
+func TestResolveCDNConditions(t *testing.T) {
+	rules := []common.CDNMaxAgeRule{{Type: common.CDN_RULE_PATH, Paths: []string{"/a"}, TTL: 60}}
+	functions := getMaxAgeFunctions(rules)
+	condition, header := functions[0], functions[1]
+	// 条件未创建时，依赖的配置等待条件创建后设置
+	newFunctions, _ := _diffCDNConfigs(nil, _resolveCDNConditions(nil, functions))
+	if len(newFunctions) != 2 || !_isCDNFunctionReady(newFunctions[0]) || _isCDNFunctionReady(newFunctions[1]) {
+		t.Errorf("new functions = %v", newFunctions)
+	}
+	header.ParentId = "10"
+	unmanaged := _testCDNConfig("20", _newCDNFunction(CDN_FUNCTION_CONDITION, "rule", `{"name": "manual"}`))
+	unmanagedHeader := _newCDNFunction(CDN_FUNCTION_RESP_HEADER, "key", "Cache-Control", "value", "no-cache")
+	unmanagedHeader.ParentId = "20"
+	configs := _filterManagedCDNConfigs([]CDNDomainConfig{
+		_testCDNConfig("10", condition),
+		_testCDNConfig("11", header),
+		unmanaged,
+		_testCDNConfig("21", unmanagedHeader),
+	})
+	if len(configs) != 2 {
+		t.Fatalf("managed configs = %v", configs)
+	}
+	newFunctions, staleIds := _diffCDNConfigs(configs, _resolveCDNConditions(configs, functions))
+	if len(newFunctions) != 0 || len(staleIds) != 0 {
+		t.Errorf("new functions = %v stale ids = %v, expect no changes", newFunctions, staleIds)
+	}
+}
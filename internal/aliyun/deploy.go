@@ -3,21 +3,141 @@ package aliyun
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"strings"
+	"time"
 
 	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
 	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	tea "github.com/alibabacloud-go/tea/tea"
 
 	"github.com/guyskk/ezfaas/internal/common"
 )
 
+const (
+	FUNCTION_UPDATE_STATUS_SUCCESSFUL string = "Successful"
+	FUNCTION_UPDATE_STATUS_FAILED     string = "Failed"
+	FUNCTION_UPDATE_STATUS_INPROGRESS string = "InProgress"
+)
+
+type HealthCheckParams struct {
+	Url                 string // 为空表示不修改健康检查
+	InitialDelaySeconds int32
+	PeriodSeconds       int32
+	TimeoutSeconds      int32
+	FailureThreshold    int32
+	SuccessThreshold    int32
+}
+
+// FC的entrypoint对应镜像ENTRYPOINT，command对应镜像CMD即启动参数
+type ContainerParams struct {
+	Port        int32    // 0表示不修改
+	Args        []string // 替换当前启动参数，nil表示不修改
+	Entrypoint  []string // nil表示不修改
+	HealthCheck HealthCheckParams
+}
+
+/* Container config of new image, unspecified settings are kept the same as current */
+func _getContainerConfig(
+	current *fc.CustomContainerConfig,
+	image string,
+	container ContainerParams,
+) *fc.CustomContainerConfig {
+	config := fc.CustomContainerConfig{Image: tea.String(image)}
+	if current != nil {
+		config.Port = current.Port
+		config.Command = current.Command
+		config.Entrypoint = current.Entrypoint
+		config.HealthCheckConfig = current.HealthCheckConfig
+		config.AccelerationType = current.AccelerationType
+		config.AcrInstanceId = current.AcrInstanceId
+	}
+	if container.Port > 0 {
+		config.Port = tea.Int32(container.Port)
+	}
+	if container.Args != nil {
+		config.Command = tea.StringSlice(container.Args)
+	}
+	if container.Entrypoint != nil {
+		config.Entrypoint = tea.StringSlice(container.Entrypoint)
+	}
+	healthCheck := container.HealthCheck
+	if healthCheck.Url != "" {
+		config.HealthCheckConfig = &fc.CustomHealthCheckConfig{
+			HttpGetUrl:          tea.String(healthCheck.Url),
+			InitialDelaySeconds: tea.Int32(healthCheck.InitialDelaySeconds),
+			PeriodSeconds:       tea.Int32(healthCheck.PeriodSeconds),
+			TimeoutSeconds:      tea.Int32(healthCheck.TimeoutSeconds),
+			FailureThreshold:    tea.Int32(healthCheck.FailureThreshold),
+			SuccessThreshold:    tea.Int32(healthCheck.SuccessThreshold),
+		}
+	}
+	return &config
+}
+
+/*
+Container config to restore previous exactly. Empty values are omitted by
+typed request which means unchanged, so they are sent explicitly.
+*/
+func _getRollbackContainerConfig(previous *fc.CustomContainerConfig) map[string]interface{} {
+	config := map[string]interface{}{
+		"image":             tea.StringValue(previous.Image),
+		"command":           append([]string{}, tea.StringSliceValue(previous.Command)...),
+		"entrypoint":        append([]string{}, tea.StringSliceValue(previous.Entrypoint)...),
+		"healthCheckConfig": map[string]interface{}{},
+	}
+	if previous.Port != nil {
+		config["port"] = tea.Int32Value(previous.Port)
+	}
+	if previous.AccelerationType != nil {
+		config["accelerationType"] = tea.StringValue(previous.AccelerationType)
+	}
+	if previous.AcrInstanceId != nil {
+		config["acrInstanceId"] = tea.StringValue(previous.AcrInstanceId)
+	}
+	if healthCheck := previous.HealthCheckConfig; healthCheck != nil {
+		config["healthCheckConfig"] = map[string]interface{}{
+			"httpGetUrl":          tea.StringValue(healthCheck.HttpGetUrl),
+			"initialDelaySeconds": tea.Int32Value(healthCheck.InitialDelaySeconds),
+			"periodSeconds":       tea.Int32Value(healthCheck.PeriodSeconds),
+			"timeoutSeconds":      tea.Int32Value(healthCheck.TimeoutSeconds),
+			"failureThreshold":    tea.Int32Value(healthCheck.FailureThreshold),
+			"successThreshold":    tea.Int32Value(healthCheck.SuccessThreshold),
+		}
+	}
+	return config
+}
+
+/* Update function with raw JSON body, the same API as client.UpdateFunction */
+func _updateFunctionRaw(client *fc.Client, functionName string, body map[string]interface{}) error {
+	params := &openapi.Params{
+		Action:      tea.String("UpdateFunction"),
+		Version:     tea.String("2023-03-30"),
+		Protocol:    tea.String("HTTPS"),
+		Pathname:    tea.String("/2023-03-30/functions/" + url.PathEscape(functionName)),
+		Method:      tea.String("PUT"),
+		AuthType:    tea.String("AK"),
+		Style:       tea.String("ROA"),
+		ReqBodyType: tea.String("json"),
+		BodyType:    tea.String("json"),
+	}
+	request := &openapi.OpenApiRequest{Body: body}
+	_, err := client.CallApi(params, request, &util.RuntimeOptions{})
+	return err
+}
+
 type _FunctionConfig struct {
 	Region                     string
 	FunctionName               string
 	ContainerImage             string
+	ContainerImageDigest       string
 	UpdateEnvironmentVariables bool
-	EnvironmentVariables       map[string]string
+	EnvUpdate                  *common.EnvUpdate
+	Triggers                   *common.TriggersConfig
+	PruneTriggers              bool
+	SmokeChecks                *common.SmokeConfig
+	Container                  ContainerParams
 	Yes                        bool
 }
 
@@ -39,6 +159,23 @@ func _getClientConfig(accessConfig *AccessConfig, endpoint string) *openapi.Conf
 	return config
 }
 
+func _getFunctionEnv(
+	client *fc.Client,
+	functionName string,
+) (map[string]string, error) {
+	response, err := client.GetFunction(&functionName, &fc.GetFunctionRequest{})
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]string{}
+	for k, v := range response.Body.EnvironmentVariables {
+		if v != nil {
+			env[k] = *v
+		}
+	}
+	return env, nil
+}
+
 func _updateFunction(
 	accessConfig *AccessConfig,
 	functionConfig *_FunctionConfig,
@@ -51,6 +188,10 @@ func _updateFunction(
 		"[INFO] ContainerImage=%s",
 		functionConfig.ContainerImage,
 	)
+	log.Printf(
+		"[INFO] ContainerImageDigest=%s",
+		functionConfig.ContainerImageDigest,
+	)
 	log.Printf(
 		"[INFO] UpdateEnvironmentVariables=%t",
 		functionConfig.UpdateEnvironmentVariables,
@@ -60,19 +201,49 @@ func _updateFunction(
 		functionConfig.Region,
 	)
 	log.Printf("[INFO] Deploy Endpoint=%s", endpoint)
-	clientConfig := _getClientConfig(accessConfig, endpoint)
-	client, err := fc.NewClient(clientConfig)
+	client, err := _newClient(accessConfig, functionConfig.Region)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := client.GetFunction(&functionConfig.FunctionName, &fc.GetFunctionRequest{})
 	if err != nil {
 		return nil, err
 	}
+	image := fmt.Sprintf(
+		"%s@%s",
+		functionConfig.ContainerImage,
+		functionConfig.ContainerImageDigest,
+	)
 	updateFunctionInput := fc.UpdateFunctionInput{
-		CustomContainerConfig: &fc.CustomContainerConfig{
-			Image: tea.String(functionConfig.ContainerImage),
-		},
+		CustomContainerConfig: _getContainerConfig(
+			previous.Body.CustomContainerConfig, image, functionConfig.Container),
+	}
+	containerConfig := updateFunctionInput.CustomContainerConfig
+	log.Printf("[INFO] Port=%d Command=%q Entrypoint=%q",
+		tea.Int32Value(containerConfig.Port),
+		tea.StringSliceValue(containerConfig.Command),
+		tea.StringSliceValue(containerConfig.Entrypoint))
+	if containerConfig.HealthCheckConfig != nil {
+		log.Printf("[INFO] HealthCheckUrl=%s",
+			tea.StringValue(containerConfig.HealthCheckConfig.HttpGetUrl))
 	}
 	if functionConfig.UpdateEnvironmentVariables {
+		log.Printf("[INFO] EnvMode=%s", functionConfig.EnvUpdate.Mode)
+		currentEnv, err := _getFunctionEnv(client, functionConfig.FunctionName)
+		if err != nil {
+			return nil, err
+		}
+		env, deleted, err := common.ResolveEnv(*functionConfig.EnvUpdate, currentEnv)
+		if err != nil {
+			return nil, err
+		}
+		common.LogDeletedEnv(functionConfig.EnvUpdate.Mode, deleted)
+		err = common.ValidateEnv(EnvRules, env)
+		if err != nil {
+			return nil, err
+		}
 		fcEnvVars := map[string]*string{}
-		for k, v := range functionConfig.EnvironmentVariables {
+		for k, v := range env {
 			fcEnvVars[k] = tea.String(v)
 		}
 		updateFunctionInput.EnvironmentVariables = fcEnvVars
@@ -80,16 +251,132 @@ func _updateFunction(
 	request := fc.UpdateFunctionRequest{
 		Body: &updateFunctionInput,
 	}
+	var triggerPlan *TriggerPlan
+	if functionConfig.Triggers != nil {
+		triggerPlan, err = _planTriggers(client, functionConfig.FunctionName,
+			functionConfig.Triggers, functionConfig.PruneTriggers)
+		if err != nil {
+			return nil, err
+		}
+		_logTriggerPlan(triggerPlan)
+	}
 	if !functionConfig.Yes {
 		if !common.ComfirmDeploy() {
 			return nil, common.ErrCanceled
 		}
 	}
 	output, err := client.UpdateFunction(&functionConfig.FunctionName, &request)
-	return output, err
+	if err != nil {
+		return nil, err
+	}
+	if triggerPlan != nil && triggerPlan.HasChanges() {
+		log.Println("[INFO] Update function triggers...")
+		err = _applyTriggerPlan(client, functionConfig.FunctionName, triggerPlan)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if functionConfig.SmokeChecks != nil {
+		err = _waitFunctionUpdated(client, functionConfig.FunctionName, 180*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		err = _runSmokeChecks(functionConfig)
+		if err != nil {
+			log.Printf("[WARN] %s", err)
+			rollbackErr := _rollbackFunction(client, functionConfig.FunctionName, previous.Body,
+				functionConfig.UpdateEnvironmentVariables)
+			if rollbackErr != nil {
+				return nil, fmt.Errorf("%s, and rollback failed: %s", err, rollbackErr)
+			}
+			return nil, fmt.Errorf("%s, rolled back to previous image and env", err)
+		}
+	}
+	return output, nil
 }
 
-func _getRegionFromRepository(repository string) (string, error) {
+/* Wait until last update of function finished */
+func _waitFunctionUpdated(client *fc.Client, functionName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	i := 1
+	for {
+		response, err := client.GetFunction(&functionName, &fc.GetFunctionRequest{})
+		if err != nil {
+			return err
+		}
+		status := tea.StringValue(response.Body.LastUpdateStatus)
+		if status == "" || status == FUNCTION_UPDATE_STATUS_SUCCESSFUL {
+			return nil
+		}
+		if status == FUNCTION_UPDATE_STATUS_FAILED {
+			return fmt.Errorf("function update failed, status=%s", status)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("function update not finished, status=%s", status)
+		}
+		if i%3 == 0 {
+			log.Printf("[INFO] Wait function updated, status=%s", status)
+		}
+		time.Sleep(time.Duration(1 * time.Second))
+		i += 1
+	}
+}
+
+func _runSmokeChecks(functionConfig *_FunctionConfig) error {
+	log.Println("[INFO] Run smoke checks...")
+	return common.RunSmokeChecks(functionConfig.SmokeChecks,
+		func(payload string) (*common.InvokeResult, error) {
+			return Invoke(InvokeParams{
+				Region:       functionConfig.Region,
+				FunctionName: functionConfig.FunctionName,
+				Payload:      payload,
+				Mode:         common.INVOKE_MODE_SYNC,
+			})
+		})
+}
+
+/*
+Redeploy previous image, and restore previous env if it was updated.
+Triggers are not rolled back.
+*/
+func _rollbackFunction(
+	client *fc.Client,
+	functionName string,
+	previous *fc.Function,
+	restoreEnv bool,
+) error {
+	if previous == nil || previous.CustomContainerConfig == nil ||
+		tea.StringValue(previous.CustomContainerConfig.Image) == "" {
+		return fmt.Errorf("no previous image")
+	}
+	containerConfig := previous.CustomContainerConfig
+	log.Printf("[INFO] Rollback ContainerImage=%s", tea.StringValue(containerConfig.Image))
+	body := map[string]interface{}{
+		"customContainerConfig": _getRollbackContainerConfig(containerConfig),
+	}
+	if restoreEnv {
+		log.Println("[INFO] Rollback function env...")
+		body["environmentVariables"] = _getRollbackEnv(previous)
+	}
+	err := _updateFunctionRaw(client, functionName, body)
+	if err != nil {
+		return err
+	}
+	return _waitFunctionUpdated(client, functionName, 180*time.Second)
+}
+
+/* Env to restore previous exactly, empty map is sent to clear added variables */
+func _getRollbackEnv(previous *fc.Function) map[string]string {
+	env := map[string]string{}
+	for k, v := range previous.EnvironmentVariables {
+		if v != nil {
+			env[k] = *v
+		}
+	}
+	return env
+}
+
+func GetRegionFromRepository(repository string) (string, error) {
 	// repository example: registry.cn-zhangjiakou.aliyuncs.com/space/name
 	parts := strings.SplitN(repository, ".", 3)
 	if len(parts) < 3 {
@@ -99,39 +386,71 @@ func _getRegionFromRepository(repository string) (string, error) {
 }
 
 type DeployParams struct {
-	FunctionName         string
-	Repository           string
-	BuildId              string
-	EnvironmentVariables *map[string]string
-	Yes                  bool
+	FunctionName  string
+	Repository    string
+	BuildId       string
+	EnvUpdate     *common.EnvUpdate      // nil表示不更新环境变量
+	Triggers      *common.TriggersConfig // nil表示不管理触发器
+	PruneTriggers bool                   // 删除未声明的非ezfaas管理的触发器
+	SmokeChecks   *common.SmokeConfig    // nil表示不执行冒烟测试
+	Container     ContainerParams
+	Yes           bool
+}
+
+func _maskSecretEnv(output *fc.UpdateFunctionResponse, update *common.EnvUpdate) {
+	if output == nil || output.Body == nil {
+		return
+	}
+	for k := range output.Body.EnvironmentVariables {
+		if common.IsSecretEnv(update, k) {
+			output.Body.EnvironmentVariables[k] = tea.String(common.SECRET_MASK)
+		}
+	}
+}
+
+type DeployResult struct {
+	ContainerImage       string
+	ContainerImageDigest string
+	Response             *fc.UpdateFunctionResponse
 }
 
-func DoDeploy(params DeployParams) (*fc.UpdateFunctionResponse, error) {
+func DoDeploy(params DeployParams) (*DeployResult, error) {
 	accessConfig, err := LoadAccessConfig()
 	if err != nil {
 		return nil, err
 	}
 	containerImage := fmt.Sprintf("%s:%s", params.Repository, params.BuildId)
-	region, err := _getRegionFromRepository(params.Repository)
+	// 使用镜像digest部署，避免tag被覆盖后函数运行的镜像发生变化
+	imageDigest, err := common.GetDockerImageDigest(containerImage)
 	if err != nil {
 		return nil, err
 	}
-	hasEnv := params.EnvironmentVariables != nil
-	env := map[string]string{}
-	if hasEnv {
-		env = *params.EnvironmentVariables
+	region, err := GetRegionFromRepository(params.Repository)
+	if err != nil {
+		return nil, err
 	}
 	functionConfig := _FunctionConfig{
 		Region:                     region,
 		FunctionName:               params.FunctionName,
 		ContainerImage:             containerImage,
-		UpdateEnvironmentVariables: hasEnv,
-		EnvironmentVariables:       env,
+		ContainerImageDigest:       imageDigest,
+		UpdateEnvironmentVariables: params.EnvUpdate != nil,
+		EnvUpdate:                  params.EnvUpdate,
+		Triggers:                   params.Triggers,
+		PruneTriggers:              params.PruneTriggers,
+		SmokeChecks:                params.SmokeChecks,
+		Container:                  params.Container,
 		Yes:                        params.Yes,
 	}
 	output, err := _updateFunction(accessConfig, &functionConfig)
 	if err != nil {
 		return nil, err
 	}
-	return output, nil
+	_maskSecretEnv(output, params.EnvUpdate)
+	result := DeployResult{
+		ContainerImage:       containerImage,
+		ContainerImageDigest: imageDigest,
+		Response:             output,
+	}
+	return &result, nil
 }
@@ -0,0 +1,81 @@
+package aliyun
+
+import (
+	"reflect"
+	"testing"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	tea "github.com/alibabacloud-go/tea/tea"
+)
+
+func TestGetContainerConfig(t *testing.T) {
+	current := &fc.CustomContainerConfig{
+		Image:      tea.String("app:v1"),
+		Port:       tea.Int32(9000),
+		Entrypoint: tea.StringSlice([]string{"python", "app.py"}),
+		Command:    tea.StringSlice([]string{"worker"}),
+	}
+	cases := []struct {
+		name       string
+		container  ContainerParams
+		entrypoint []string
+		args       []string
+	}{
+		{"unchanged", ContainerParams{}, []string{"python", "app.py"}, []string{"worker"}},
+		{"entrypoint", ContainerParams{Entrypoint: []string{"node", "app.js"}},
+			[]string{"node", "app.js"}, []string{"worker"}},
+		// 启动参数替换当前参数，不会在多次部署中累加
+		{"args replaced", ContainerParams{Args: []string{"web"}}, []string{"python", "app.py"}, []string{"web"}},
+		{"args cleared", ContainerParams{Args: []string{}}, []string{"python", "app.py"}, []string{}},
+	}
+	for _, c := range cases {
+		config := _getContainerConfig(current, "app:v2", c.container)
+		entrypoint := tea.StringSliceValue(config.Entrypoint)
+		if !reflect.DeepEqual(entrypoint, c.entrypoint) {
+			t.Errorf("%s: entrypoint = %q, expect %q", c.name, entrypoint, c.entrypoint)
+		}
+		args := tea.StringSliceValue(config.Command)
+		if !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%s: args = %q, expect %q", c.name, args, c.args)
+		}
+		if tea.StringValue(config.Image) != "app:v2" || tea.Int32Value(config.Port) != 9000 {
+			t.Errorf("%s: image or port changed", c.name)
+		}
+	}
+}
+
+func TestGetRollbackContainerConfig(t *testing.T) {
+	config := _getRollbackContainerConfig(&fc.CustomContainerConfig{
+		Image: tea.String("app:v1"),
+		Port:  tea.Int32(9000),
+	})
+	expect := map[string]interface{}{
+		"image":             "app:v1",
+		"port":              int32(9000),
+		"command":           []string{},
+		"entrypoint":        []string{},
+		"healthCheckConfig": map[string]interface{}{},
+	}
+	if !reflect.DeepEqual(config, expect) {
+		t.Errorf("config = %v, expect %v", config, expect)
+	}
+}
+
+func TestGetRollbackEnv(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous *fc.Function
+		expect   map[string]string
+	}{
+		{"env", &fc.Function{EnvironmentVariables: map[string]*string{"A": tea.String("1")}},
+			map[string]string{"A": "1"}},
+		// 之前没有变量时发送空值，清除部署新增的变量
+		{"empty", &fc.Function{}, map[string]string{}},
+	}
+	for _, c := range cases {
+		env := _getRollbackEnv(c.previous)
+		if !reflect.DeepEqual(env, c.expect) {
+			t.Errorf("%s: env = %v, expect %v", c.name, env, c.expect)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package aliyun
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	tea "github.com/alibabacloud-go/tea/tea"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+type CustomDomainParams struct {
+	Region        string
+	CustomDomains *common.CustomDomainsConfig
+	Yes           bool
+}
+
+type _CustomDomainChange struct {
+	Domain      string
+	IsCreate    bool
+	Protocol    string
+	RouteConfig *fc.RouteConfig
+	CertConfig  *fc.CertConfig // nil表示不修改证书
+}
+
+func _newClient(accessConfig *AccessConfig, region string) (*fc.Client, error) {
+	endpoint := _getEndpoint(accessConfig.ALIBABA_CLOUD_ACCOUNT_ID, region)
+	return fc.NewClient(_getClientConfig(accessConfig, endpoint))
+}
+
+func _listCustomDomains(client *fc.Client) ([]*fc.CustomDomain, error) {
+	var domains []*fc.CustomDomain
+	var nextToken *string
+	for {
+		response, err := client.ListCustomDomains(&fc.ListCustomDomainsRequest{
+			Limit:     tea.Int32(100),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, response.Body.CustomDomains...)
+		nextToken = response.Body.NextToken
+		if nextToken == nil || *nextToken == "" {
+			break
+		}
+	}
+	return domains, nil
+}
+
+func _getRouteConfig(routes []common.RouteSpec) *fc.RouteConfig {
+	var pathConfigs []*fc.PathConfig
+	for _, route := range routes {
+		qualifier := route.Qualifier
+		if qualifier == "" {
+			qualifier = "LATEST"
+		}
+		pathConfigs = append(pathConfigs, &fc.PathConfig{
+			Path:         tea.String(route.Path),
+			FunctionName: tea.String(route.Function),
+			Qualifier:    tea.String(qualifier),
+			Methods:      tea.StringSlice(route.Methods),
+		})
+	}
+	return &fc.RouteConfig{Routes: pathConfigs}
+}
+
+func _formatRoutes(routeConfig *fc.RouteConfig) []string {
+	var lines []string
+	if routeConfig == nil {
+		return lines
+	}
+	for _, route := range routeConfig.Routes {
+		lines = append(lines, fmt.Sprintf("%s -> %s@%s %s",
+			tea.StringValue(route.Path),
+			tea.StringValue(route.FunctionName),
+			tea.StringValue(route.Qualifier),
+			strings.Join(tea.StringSliceValue(route.Methods), ","),
+		))
+	}
+	return lines
+}
+
+/* Cert name only allows letters, digits, - and _ */
+func _getCertName(domain string, cert *common.Certificate) string {
+	name := strings.NewReplacer(".", "-", "*", "x").Replace(domain)
+	return fmt.Sprintf("ezfaas-%s-%s", name, cert.Leaf.NotAfter.Format("20060102"))
+}
+
+func _getCertConfig(spec common.CustomDomainSpec) (*fc.CertConfig, error) {
+	cert, err := common.LoadCertificate(spec.CertFile, spec.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	err = common.CheckCertificate(cert, spec.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", spec.Domain, err)
+	}
+	log.Printf("[INFO] Certificate of %s %s", spec.Domain, cert.String())
+	return &fc.CertConfig{
+		CertName:    tea.String(_getCertName(spec.Domain, cert)),
+		Certificate: tea.String(cert.CertPEM),
+		PrivateKey:  tea.String(cert.KeyPEM),
+	}, nil
+}
+
+/* Plan changes of the domain, certConfig is nil for HTTP only domain, return nil if no changes */
+func _planCustomDomain(
+	spec common.CustomDomainSpec,
+	current *fc.CustomDomain,
+	certConfig *fc.CertConfig,
+) *_CustomDomainChange {
+	change := _CustomDomainChange{
+		Domain:      spec.Domain,
+		IsCreate:    current == nil,
+		Protocol:    strings.ToUpper(spec.Protocol),
+		RouteConfig: _getRouteConfig(spec.Routes),
+		CertConfig:  certConfig,
+	}
+	if current == nil {
+		return &change
+	}
+	isChanged := false
+	if change.Protocol != strings.ToUpper(tea.StringValue(current.Protocol)) {
+		isChanged = true
+	}
+	newRoutes := strings.Join(_formatRoutes(change.RouteConfig), "\n")
+	if newRoutes != strings.Join(_formatRoutes(current.RouteConfig), "\n") {
+		isChanged = true
+	}
+	if change.CertConfig != nil {
+		currentCert := ""
+		if current.CertConfig != nil {
+			currentCert = strings.TrimSpace(tea.StringValue(current.CertConfig.Certificate))
+		}
+		if currentCert == strings.TrimSpace(*change.CertConfig.Certificate) {
+			change.CertConfig = nil
+		} else {
+			isChanged = true
+		}
+	}
+	if !isChanged {
+		return nil
+	}
+	return &change
+}
+
+func _logCustomDomainChange(change *_CustomDomainChange) {
+	action := "update"
+	if change.IsCreate {
+		action = "create"
+	}
+	log.Printf("[INFO] CustomDomain %s %s protocol=%s updateCert=%t",
+		action, change.Domain, change.Protocol, change.CertConfig != nil)
+	for _, line := range _formatRoutes(change.RouteConfig) {
+		log.Printf("[INFO]   %s", line)
+	}
+}
+
+func _applyCustomDomainChange(client *fc.Client, change *_CustomDomainChange) error {
+	if change.IsCreate {
+		_, err := client.CreateCustomDomain(&fc.CreateCustomDomainRequest{
+			Body: &fc.CreateCustomDomainInput{
+				DomainName:  tea.String(change.Domain),
+				Protocol:    tea.String(change.Protocol),
+				RouteConfig: change.RouteConfig,
+				CertConfig:  change.CertConfig,
+			},
+		})
+		return err
+	}
+	_, err := client.UpdateCustomDomain(&change.Domain, &fc.UpdateCustomDomainRequest{
+		Body: &fc.UpdateCustomDomainInput{
+			Protocol:    tea.String(change.Protocol),
+			RouteConfig: change.RouteConfig,
+			CertConfig:  change.CertConfig,
+		},
+	})
+	return err
+}
+
+/*
+Create or update custom domains to be the same as declared, domains not
+declared are not changed. Certificate is updated only if it changed.
+*/
+func UpdateCustomDomains(params CustomDomainParams) error {
+	accessConfig, err := LoadAccessConfig()
+	if err != nil {
+		return err
+	}
+	client, err := _newClient(accessConfig, params.Region)
+	if err != nil {
+		return err
+	}
+	current, err := _listCustomDomains(client)
+	if err != nil {
+		return err
+	}
+	currentMap := map[string]*fc.CustomDomain{}
+	for _, domain := range current {
+		currentMap[tea.StringValue(domain.DomainName)] = domain
+	}
+	var changes []*_CustomDomainChange
+	for _, spec := range params.CustomDomains.CustomDomains {
+		var certConfig *fc.CertConfig
+		if spec.IsHTTPS() {
+			certConfig, err = _getCertConfig(spec)
+			if err != nil {
+				return err
+			}
+		}
+		change := _planCustomDomain(spec, currentMap[spec.Domain], certConfig)
+		if change == nil {
+			log.Printf("[INFO] CustomDomain %s no changes", spec.Domain)
+			continue
+		}
+		_logCustomDomainChange(change)
+		changes = append(changes, change)
+	}
+	if len(changes) <= 0 {
+		return nil
+	}
+	if !params.Yes {
+		if !common.Comfirm("Confirm Update Custom Domains") {
+			return common.ErrCanceled
+		}
+	}
+	for _, change := range changes {
+		err := _applyCustomDomainChange(client, change)
+		if err != nil {
+			return fmt.Errorf("custom domain %s: %s", change.Domain, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package aliyun
+
+import (
+	"reflect"
+	"testing"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	tea "github.com/alibabacloud-go/tea/tea"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+func TestPlanCustomDomain(t *testing.T) {
+	spec := common.CustomDomainSpec{
+		Domain:   "api.example.com",
+		Protocol: "http,https",
+		Routes: []common.RouteSpec{
+			{Path: "/api/*", Function: "demo", Methods: []string{"GET", "POST"}},
+		},
+	}
+	certConfig := &fc.CertConfig{
+		CertName:    tea.String("ezfaas-api-example-com-20270101"),
+		Certificate: tea.String("-----BEGIN CERTIFICATE-----\nnew\n-----END CERTIFICATE-----\n"),
+		PrivateKey:  tea.String("key"),
+	}
+	// 和spec一致的线上配置，证书只有末尾空白不同
+	newCurrent := func() *fc.CustomDomain {
+		return &fc.CustomDomain{
+			DomainName: tea.String("api.example.com"),
+			Protocol:   tea.String("HTTP,HTTPS"),
+			RouteConfig: &fc.RouteConfig{Routes: []*fc.PathConfig{{
+				Path:         tea.String("/api/*"),
+				FunctionName: tea.String("demo"),
+				Qualifier:    tea.String("LATEST"),
+				Methods:      tea.StringSlice([]string{"GET", "POST"}),
+			}}},
+			CertConfig: &fc.CertConfig{
+				Certificate: tea.String("-----BEGIN CERTIFICATE-----\nnew\n-----END CERTIFICATE-----"),
+			},
+		}
+	}
+	cases := []struct {
+		name       string
+		modify     func(current *fc.CustomDomain) *fc.CustomDomain
+		certConfig *fc.CertConfig
+		isChanged  bool
+		isCreate   bool
+		updateCert bool
+	}{
+		{"create", func(current *fc.CustomDomain) *fc.CustomDomain {
+			return nil
+		}, certConfig, true, true, true},
+		{"no changes", func(current *fc.CustomDomain) *fc.CustomDomain {
+			return current
+		}, certConfig, false, false, false},
+		{"route path changed", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.RouteConfig.Routes[0].Path = tea.String("/*")
+			return current
+		}, certConfig, true, false, false},
+		{"route qualifier changed", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.RouteConfig.Routes[0].Qualifier = tea.String("prod")
+			return current
+		}, certConfig, true, false, false},
+		{"route methods changed", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.RouteConfig.Routes[0].Methods = tea.StringSlice([]string{"GET"})
+			return current
+		}, certConfig, true, false, false},
+		{"route removed", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.RouteConfig.Routes = append(current.RouteConfig.Routes, &fc.PathConfig{
+				Path:         tea.String("/old/*"),
+				FunctionName: tea.String("old"),
+				Qualifier:    tea.String("LATEST"),
+			})
+			return current
+		}, certConfig, true, false, false},
+		{"protocol changed", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.Protocol = tea.String("HTTP")
+			return current
+		}, certConfig, true, false, false},
+		{"cert changed", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.CertConfig.Certificate = tea.String("-----BEGIN CERTIFICATE-----\nold\n-----END CERTIFICATE-----")
+			return current
+		}, certConfig, true, false, true},
+		{"cert missing", func(current *fc.CustomDomain) *fc.CustomDomain {
+			current.CertConfig = nil
+			return current
+		}, certConfig, true, false, true},
+	}
+	for _, c := range cases {
+		change := _planCustomDomain(spec, c.modify(newCurrent()), c.certConfig)
+		if (change != nil) != c.isChanged {
+			t.Errorf("%s: expect changed=%t, got %+v", c.name, c.isChanged, change)
+			continue
+		}
+		if change == nil {
+			continue
+		}
+		if change.IsCreate != c.isCreate {
+			t.Errorf("%s: expect create=%t", c.name, c.isCreate)
+		}
+		if (change.CertConfig != nil) != c.updateCert {
+			t.Errorf("%s: expect updateCert=%t", c.name, c.updateCert)
+		}
+		if change.Protocol != "HTTP,HTTPS" {
+			t.Errorf("%s: protocol=%s", c.name, change.Protocol)
+		}
+		expectRoutes := []string{"/api/* -> demo@LATEST GET,POST"}
+		if routes := _formatRoutes(change.RouteConfig); !reflect.DeepEqual(routes, expectRoutes) {
+			t.Errorf("%s: routes=%v", c.name, routes)
+		}
+	}
+}
+
+func TestPlanCustomDomainHTTP(t *testing.T) {
+	spec := common.CustomDomainSpec{
+		Domain:   "www.example.com",
+		Protocol: "HTTP",
+		Routes:   []common.RouteSpec{{Path: "/*", Function: "web", Qualifier: "prod"}},
+	}
+	current := &fc.CustomDomain{
+		DomainName: tea.String("www.example.com"),
+		Protocol:   tea.String("HTTP"),
+		RouteConfig: &fc.RouteConfig{Routes: []*fc.PathConfig{{
+			Path:         tea.String("/*"),
+			FunctionName: tea.String("web"),
+			Qualifier:    tea.String("prod"),
+		}}},
+		CertConfig: &fc.CertConfig{Certificate: tea.String("old")},
+	}
+	// HTTP域名不比较证书
+	if change := _planCustomDomain(spec, current, nil); change != nil {
+		t.Errorf("expect no changes, got %+v", change)
+	}
+}
@@ -0,0 +1,16 @@
+package aliyun
+
+import (
+	"regexp"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+// https://help.aliyun.com/zh/functioncompute/fc-3-0/user-guide/environment-variables
+var EnvRules = common.EnvRules{
+	Platform:         "aliyun fc",
+	NameRegex:        regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`),
+	NameDescription:  "must start with letter or _ and contain only letters, digits and _",
+	ReservedPrefixes: []string{"FC_", "ALIBABA_CLOUD_"},
+	MaxTotalSize:     4 * 1024,
+}
@@ -0,0 +1,184 @@
+package aliyun
+
+import (
+	"encoding/base64"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	tea "github.com/alibabacloud-go/tea/tea"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+/*
+Payload templates of FC 3.0 trigger events, apigw is event of http trigger
+and cos is event of OSS trigger.
+https://help.aliyun.com/zh/functioncompute/fc-3-0/user-guide/trigger-event-formats
+*/
+var PayloadTemplates = common.PayloadTemplates{
+	common.PAYLOAD_TEMPLATE_TIMER: `{
+    "triggerTime": "2024-01-01T00:00:00Z",
+    "triggerName": "timer",
+    "payload": ""
+}`,
+	common.PAYLOAD_TEMPLATE_APIGW: `{
+    "version": "v1",
+    "rawPath": "/",
+    "body": "",
+    "isBase64Encoded": false,
+    "headers": {
+        "Accept": "*/*",
+        "User-Agent": "ezfaas"
+    },
+    "queryParameters": {},
+    "requestContext": {
+        "accountId": "123456789",
+        "domainName": "example.cn-hangzhou.fcapp.run",
+        "domainPrefix": "example",
+        "http": {
+            "method": "GET",
+            "path": "/",
+            "protocol": "HTTP/1.1",
+            "sourceIp": "127.0.0.1",
+            "userAgent": "ezfaas"
+        },
+        "requestId": "1-6582d5f6-15e5d2f0-1f9e3f5a2b6c",
+        "time": "01/Jan/2024:00:00:00 +0000",
+        "timeEpoch": "1704067200000"
+    }
+}`,
+	common.PAYLOAD_TEMPLATE_COS: `{
+    "events": [{
+        "eventName": "ObjectCreated:PutObject",
+        "eventSource": "acs:oss",
+        "eventTime": "2024-01-01T00:00:00.000Z",
+        "eventVersion": "1.0",
+        "oss": {
+            "bucket": {
+                "arn": "acs:oss:cn-hangzhou:123456789:example",
+                "name": "example",
+                "ownerIdentity": "123456789"
+            },
+            "object": {
+                "deltaSize": 1029,
+                "eTag": "0C5A7BF27C1A53C3B5A4B5B0EC1E9A6D",
+                "key": "testfile",
+                "size": 1029
+            },
+            "ossSchemaVersion": "1.0",
+            "ruleId": "9adac8e253828f4f7c0466d941fa3db81161****"
+        },
+        "region": "cn-hangzhou",
+        "requestParameters": {"sourceIPAddress": "127.0.0.1"},
+        "responseElements": {"requestId": "58F9FF2D3DF792092E12044C"},
+        "userIdentity": {"principalId": "123456789"}
+    }]
+}`,
+}
+
+type InvokeParams struct {
+	Region       string
+	FunctionName string
+	Qualifier    string // 为空表示LATEST
+	Payload      string
+	Mode         string // sync 或 async
+}
+
+/* Header names of tea response are lower cased */
+func _getHeader(headers map[string]*string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return tea.StringValue(v)
+		}
+	}
+	return ""
+}
+
+/* Number headers may be fractional, eg: x-fc-max-memory-usage */
+func _getHeaderFloat(headers map[string]*string, name string) float64 {
+	value, _ := strconv.ParseFloat(_getHeader(headers, name), 64)
+	return value
+}
+
+func _getFunctionMemorySize(client *fc.Client, functionName string, qualifier string) (int64, error) {
+	request := fc.GetFunctionRequest{}
+	if qualifier != "" {
+		request.Qualifier = tea.String(qualifier)
+	}
+	response, err := client.GetFunction(&functionName, &request)
+	if err != nil {
+		return 0, err
+	}
+	return int64(tea.Int32Value(response.Body.MemorySize)), nil
+}
+
+/*
+Invoke function by FC InvokeFunction API, log tail is returned only in sync mode.
+https://help.aliyun.com/zh/functioncompute/fc-3-0/developer-reference/api-fc-2023-03-30-invokefunction
+*/
+func Invoke(params InvokeParams) (*common.InvokeResult, error) {
+	accessConfig, err := LoadAccessConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := _newClient(accessConfig, params.Region)
+	if err != nil {
+		return nil, err
+	}
+	request := fc.InvokeFunctionRequest{
+		Body: strings.NewReader(params.Payload),
+	}
+	if params.Qualifier != "" {
+		request.Qualifier = tea.String(params.Qualifier)
+	}
+	headers := fc.InvokeFunctionHeaders{}
+	if params.Mode == common.INVOKE_MODE_ASYNC {
+		headers.XFcInvocationType = tea.String("Async")
+	} else {
+		headers.XFcInvocationType = tea.String("Sync")
+		headers.XFcLogType = tea.String("Tail")
+	}
+	log.Printf("[INFO] Invoke Function=%s Qualifier=%s Mode=%s",
+		params.FunctionName, params.Qualifier, params.Mode)
+	response, err := client.InvokeFunctionWithOptions(
+		&params.FunctionName, &request, &headers, &util.RuntimeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if response.Body != nil {
+		defer response.Body.Close()
+		body, err = io.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := common.InvokeResult{
+		RequestId: _getHeader(response.Headers, "x-fc-request-id"),
+		Body:      string(body),
+		Duration:  _getHeaderFloat(response.Headers, "x-fc-invocation-duration"),
+		MemUsage:  _getHeaderFloat(response.Headers, "x-fc-max-memory-usage"),
+		Error:     _getHeader(response.Headers, "x-fc-error-type"),
+	}
+	// 响应中没有计费时长，不做推算
+	logResult := _getHeader(response.Headers, "x-fc-log-result")
+	if logResult != "" {
+		data, err := base64.StdEncoding.DecodeString(logResult)
+		if err != nil {
+			return nil, err
+		}
+		result.Log = string(data)
+	}
+	if params.Mode != common.INVOKE_MODE_ASYNC {
+		result.MemorySize, err = _getFunctionMemorySize(
+			client, params.FunctionName, params.Qualifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
@@ -0,0 +1,28 @@
+package aliyun
+
+import (
+	"testing"
+
+	tea "github.com/alibabacloud-go/tea/tea"
+)
+
+func TestGetHeaderFloat(t *testing.T) {
+	headers := map[string]*string{
+		"x-fc-invocation-duration": tea.String("12"),
+		"x-fc-max-memory-usage":    tea.String("27.45"),
+	}
+	cases := []struct {
+		name   string
+		expect float64
+	}{
+		{"X-Fc-Invocation-Duration", 12},
+		{"x-fc-max-memory-usage", 27.45},
+		{"x-fc-missing", 0},
+	}
+	for _, c := range cases {
+		value := _getHeaderFloat(headers, c.name)
+		if value != c.expect {
+			t.Errorf("%s = %v, expect %v", c.name, value, c.expect)
+		}
+	}
+}
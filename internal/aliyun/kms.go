@@ -0,0 +1,44 @@
+package aliyun
+
+import (
+	"fmt"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+/* Resolve kms://name#key reference from Aliyun KMS Secrets Manager */
+type KMSSecretResolver struct {
+	Region string
+}
+
+func (r KMSSecretResolver) ResolveSecret(ref common.SecretRef) (string, error) {
+	region := ref.Query.Get("region")
+	if region == "" {
+		region = r.Region
+	}
+	if region == "" {
+		return "", fmt.Errorf("region of %s is required", ref.String())
+	}
+	accessConfig, err := LoadAccessConfig()
+	if err != nil {
+		return "", err
+	}
+	query := map[string]interface{}{
+		"SecretName": ref.Name,
+	}
+	if version := ref.Query.Get("version"); version != "" {
+		query["VersionId"] = version
+	} else {
+		query["VersionStage"] = "ACSCurrent"
+	}
+	endpoint := fmt.Sprintf("kms.%s.aliyuncs.com", region)
+	body, err := _callApi(accessConfig, endpoint, "2016-01-20", "GetSecretValue", query)
+	if err != nil {
+		return "", err
+	}
+	secret, ok := body["SecretData"].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no data", ref.Name)
+	}
+	return common.GetSecretKeyValue(ref, secret)
+}
@@ -0,0 +1,87 @@
+package aliyun
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+// 自定义容器默认监听端口CAPort
+const FC_DEFAULT_CA_PORT int = 9000
+
+/*
+Runtime conventions of FC 3.0 custom container, event function is invoked by
+POST /invoke, http trigger requests are forwarded directly.
+https://help.aliyun.com/zh/functioncompute/fc-3-0/user-guide/custom-container-function
+*/
+func GetLocalRuntime(config common.LocalRunConfig) common.LocalRuntime {
+	port := config.Port
+	if port <= 0 {
+		port = FC_DEFAULT_CA_PORT
+	}
+	memorySize := fmt.Sprintf("%d", config.MemorySize)
+	timeout := fmt.Sprintf("%d", config.Timeout)
+	return common.LocalRuntime{
+		Platform: "aliyun",
+		Env: map[string]string{
+			"FC_FUNCTION_NAME":        config.FunctionName,
+			"FC_FUNCTION_MEMORY_SIZE": memorySize,
+			"FC_QUALIFIER":            "LATEST",
+			"FC_REGION":               config.Region,
+			"FC_CUSTOM_LISTEN_PORT":   fmt.Sprintf("%d", port),
+			"FC_INSTANCE_ID":          "local",
+		},
+		ContainerPort: port,
+		EventPath:     "/invoke",
+		EventHeaders: map[string]string{
+			"x-fc-function-name":    config.FunctionName,
+			"x-fc-function-memory":  memorySize,
+			"x-fc-function-timeout": timeout,
+			"x-fc-qualifier":        "LATEST",
+			"x-fc-region":           config.Region,
+			"x-fc-instance-id":      "local",
+		},
+		RequestIdHeader:  "x-fc-request-id",
+		HTTPEventEncoder: EncodeHTTPTriggerEvent,
+	}
+}
+
+/*
+Convert http request to HTTP trigger event of FC 3.0.
+https://help.aliyun.com/zh/functioncompute/fc-3-0/user-guide/http-trigger-invoking-function
+*/
+func EncodeHTTPTriggerEvent(
+	request *http.Request,
+	body []byte,
+	requestId string,
+) map[string]interface{} {
+	eventBody, isBase64Encoded := common.EncodeEventBody(body)
+	now := time.Now()
+	domainPrefix := strings.SplitN(request.Host, ".", 2)[0]
+	return map[string]interface{}{
+		"version":         "v1",
+		"rawPath":         request.URL.Path,
+		"body":            eventBody,
+		"isBase64Encoded": isBase64Encoded,
+		"headers":         common.JoinHeaderValues(request.Header, false),
+		"queryParameters": common.FirstQueryValues(request.URL.Query()),
+		"requestContext": map[string]interface{}{
+			"accountId":    "local",
+			"domainName":   request.Host,
+			"domainPrefix": domainPrefix,
+			"http": map[string]interface{}{
+				"method":    request.Method,
+				"path":      request.URL.Path,
+				"protocol":  request.Proto,
+				"sourceIp":  common.GetSourceIp(request),
+				"userAgent": request.UserAgent(),
+			},
+			"requestId": requestId,
+			"time":      now.Format("02/Jan/2006:15:04:05 -0700"),
+			"timeEpoch": fmt.Sprintf("%d", now.UnixNano()/int64(time.Millisecond)),
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package aliyun
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	tea "github.com/alibabacloud-go/tea/tea"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+type LogsParams struct {
+	Region       string
+	FunctionName string
+	Qualifier    string // 为空表示所有版本
+}
+
+func _getFunction(params LogsParams) (*AccessConfig, *fc.Function, error) {
+	accessConfig, err := LoadAccessConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := _newClient(accessConfig, params.Region)
+	if err != nil {
+		return nil, nil, err
+	}
+	request := fc.GetFunctionRequest{}
+	if params.Qualifier != "" {
+		request.Qualifier = tea.String(params.Qualifier)
+	}
+	response, err := client.GetFunction(&params.FunctionName, &request)
+	if err != nil {
+		return nil, nil, err
+	}
+	return accessConfig, response.Body, nil
+}
+
+/*
+Last modified time of function, both code and config updates change it.
+Old instances may still output logs after it, so it is not a version filter.
+*/
+func GetLastModifiedTime(params LogsParams) (time.Time, error) {
+	_, function, err := _getFunction(params)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, tea.StringValue(function.LastModifiedTime))
+}
+
+func _getLogField(item map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		if value, ok := item[name]; ok {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	return ""
+}
+
+/*
+Get log fetcher of SLS project and logstore in LogConfig of function.
+https://help.aliyun.com/zh/functioncompute/fc-3-0/user-guide/configure-the-logging-function
+*/
+func GetLogFetcher(params LogsParams) (common.LogFetcher, error) {
+	accessConfig, function, err := _getFunction(params)
+	if err != nil {
+		return nil, err
+	}
+	logConfig := function.LogConfig
+	if logConfig == nil || tea.StringValue(logConfig.Project) == "" ||
+		tea.StringValue(logConfig.Logstore) == "" {
+		return nil, fmt.Errorf("function %s has no log config", params.FunctionName)
+	}
+	project := tea.StringValue(logConfig.Project)
+	logstore := tea.StringValue(logConfig.Logstore)
+	query := fmt.Sprintf("functionName: %s", params.FunctionName)
+	if params.Qualifier != "" {
+		query += fmt.Sprintf(" and qualifier: %s", params.Qualifier)
+	}
+	fetch := func(start time.Time, end time.Time) ([]common.FunctionLog, error) {
+		items, err := _getSLSLogs(accessConfig, params.Region, project, logstore, start, end, query)
+		if err != nil {
+			return nil, err
+		}
+		var logs []common.FunctionLog
+		for _, item := range items {
+			seconds, err := strconv.ParseInt(_getLogField(item, "__time__"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid log time: %s", err)
+			}
+			logs = append(logs, common.FunctionLog{
+				Time:      time.Unix(seconds, 0),
+				RequestId: _getLogField(item, "requestId", "requestID"),
+				Message:   _getLogField(item, "message", "content"),
+			})
+		}
+		return logs, nil
+	}
+	return fetch, nil
+}
@@ -0,0 +1,130 @@
+package aliyun
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Call SLS API with LOG signature, SLS is not supported by the openapi client.
+https://help.aliyun.com/zh/sls/developer-reference/request-signatures
+*/
+func _callSLSApi(
+	accessConfig *AccessConfig,
+	region string,
+	project string,
+	path string,
+	query map[string]string,
+) ([]byte, error) {
+	var keys []string
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	values := url.Values{}
+	for _, k := range keys {
+		canonicalQuery = append(canonicalQuery, fmt.Sprintf("%s=%s", k, query[k]))
+		values.Set(k, query[k])
+	}
+	date := time.Now().UTC().Format(http.TimeFormat)
+	headers := map[string]string{
+		"x-log-apiversion":      "0.6.0",
+		"x-log-bodyrawsize":     "0",
+		"x-log-signaturemethod": "hmac-sha1",
+	}
+	var headerKeys []string
+	for k := range headers {
+		headerKeys = append(headerKeys, k)
+	}
+	sort.Strings(headerKeys)
+	var canonicalHeaders []string
+	for _, k := range headerKeys {
+		canonicalHeaders = append(canonicalHeaders, fmt.Sprintf("%s:%s", k, headers[k]))
+	}
+	resource := path
+	if len(canonicalQuery) > 0 {
+		resource += "?" + strings.Join(canonicalQuery, "&")
+	}
+	signText := strings.Join([]string{
+		http.MethodGet, "", "", date, strings.Join(canonicalHeaders, "\n"), resource,
+	}, "\n")
+	mac := hmac.New(sha1.New, []byte(accessConfig.ALIBABA_CLOUD_ACCESS_KEY_SECRET))
+	mac.Write([]byte(signText))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	requestUrl := fmt.Sprintf("https://%s.%s.log.aliyuncs.com%s?%s",
+		project, region, path, values.Encode())
+	request, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+	request.Header.Set("Date", date)
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"LOG %s:%s", accessConfig.ALIBABA_CLOUD_ACCESS_KEY_ID, signature))
+	client := http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SLS %s status %d: %s", path, response.StatusCode, string(data))
+	}
+	return data, nil
+}
+
+/* Query logs of logstore, time range is [from, to) in seconds */
+func _getSLSLogs(
+	accessConfig *AccessConfig,
+	region string,
+	project string,
+	logstore string,
+	from time.Time,
+	to time.Time,
+	query string,
+) ([]map[string]interface{}, error) {
+	var logs []map[string]interface{}
+	line := 100
+	offset := 0
+	for {
+		data, err := _callSLSApi(accessConfig, region, project, "/logstores/"+logstore, map[string]string{
+			"type":    "log",
+			"from":    fmt.Sprintf("%d", from.Unix()),
+			"to":      fmt.Sprintf("%d", to.Unix()),
+			"query":   query,
+			"line":    fmt.Sprintf("%d", line),
+			"offset":  fmt.Sprintf("%d", offset),
+			"reverse": "false",
+		})
+		if err != nil {
+			return nil, err
+		}
+		var items []map[string]interface{}
+		err = json.Unmarshal(data, &items)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, items...)
+		offset += len(items)
+		if len(items) < line {
+			break
+		}
+	}
+	return logs, nil
+}
@@ -0,0 +1,248 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+	tea "github.com/alibabacloud-go/tea/tea"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+var _defaultHTTPMethods = []string{
+	"GET", "POST", "PUT", "DELETE", "HEAD", "PATCH", "OPTIONS",
+}
+
+type TriggerUpdate struct {
+	TriggerName string
+	TriggerType string
+	Input       *fc.UpdateTriggerInput
+}
+
+type TriggerPlan struct {
+	Create    []*fc.CreateTriggerInput
+	Update    []TriggerUpdate
+	Delete    []*fc.Trigger
+	Unmanaged []*fc.Trigger // 未声明且不由ezfaas管理，保留不变
+}
+
+func (p *TriggerPlan) HasChanges() bool {
+	return len(p.Create) > 0 || len(p.Update) > 0 || len(p.Delete) > 0
+}
+
+func _getTriggerKey(trigger *fc.Trigger) string {
+	return fmt.Sprintf("%s/%s",
+		tea.StringValue(trigger.TriggerType), tea.StringValue(trigger.TriggerName))
+}
+
+func (p *TriggerPlan) Changes() []common.TriggerChange {
+	var changes []common.TriggerChange
+	for _, trigger := range p.Delete {
+		changes = append(changes, common.TriggerChange{
+			Action: common.TRIGGER_ACTION_DELETE,
+			Key:    _getTriggerKey(trigger),
+		})
+	}
+	for _, input := range p.Create {
+		changes = append(changes, common.TriggerChange{
+			Action: common.TRIGGER_ACTION_CREATE,
+			Key:    fmt.Sprintf("%s/%s", *input.TriggerType, *input.TriggerName),
+			Detail: fmt.Sprintf("qualifier=%s config=%s", *input.Qualifier, *input.TriggerConfig),
+		})
+	}
+	for _, update := range p.Update {
+		changes = append(changes, common.TriggerChange{
+			Action: common.TRIGGER_ACTION_UPDATE,
+			Key:    fmt.Sprintf("%s/%s", update.TriggerType, update.TriggerName),
+			Detail: fmt.Sprintf("qualifier=%s config=%s",
+				*update.Input.Qualifier, *update.Input.TriggerConfig),
+		})
+	}
+	for _, trigger := range p.Unmanaged {
+		changes = append(changes, common.TriggerChange{
+			Action: common.TRIGGER_ACTION_KEEP,
+			Key:    _getTriggerKey(trigger),
+		})
+	}
+	return changes
+}
+
+/* Get trigger config of FC 3.0, only http and timer triggers are supported */
+func _getTriggerConfig(spec common.TriggerSpec) (string, error) {
+	if spec.Desc != "" {
+		return spec.Desc, nil
+	}
+	var config interface{}
+	switch spec.Type {
+	case common.TRIGGER_HTTP:
+		if !spec.IsEnabled() {
+			return "", fmt.Errorf("http trigger can not be disabled, remove it instead")
+		}
+		authType := spec.AuthType
+		if authType == "" {
+			authType = "anonymous"
+		}
+		methods := spec.Methods
+		if len(methods) <= 0 {
+			methods = _defaultHTTPMethods
+		}
+		config = map[string]interface{}{
+			"authType": authType,
+			"methods":  methods,
+		}
+	case common.TRIGGER_TIMER:
+		if spec.Cron == "" {
+			return "", fmt.Errorf("cron is required")
+		}
+		config = map[string]interface{}{
+			"cronExpression": spec.Cron,
+			"enable":         spec.IsEnabled(),
+			"payload":        spec.Payload,
+		}
+	default:
+		return "", fmt.Errorf("trigger type %q is not supported, use desc instead", spec.Type)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func _listTriggers(client *fc.Client, functionName string) ([]*fc.Trigger, error) {
+	var triggers []*fc.Trigger
+	var nextToken *string
+	for {
+		response, err := client.ListTriggers(&functionName, &fc.ListTriggersRequest{
+			Limit:     tea.Int32(100),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, response.Body.Triggers...)
+		nextToken = response.Body.NextToken
+		if nextToken == nil || *nextToken == "" {
+			break
+		}
+	}
+	return triggers, nil
+}
+
+/*
+Plan changes to make triggers same as declared, existed triggers are updated
+in place and tagged as managed by ezfaas. Undeclared triggers not managed by
+ezfaas are deleted only if prune.
+*/
+func _planTriggers(
+	client *fc.Client,
+	functionName string,
+	config *common.TriggersConfig,
+	prune bool,
+) (*TriggerPlan, error) {
+	current, err := _listTriggers(client, functionName)
+	if err != nil {
+		return nil, err
+	}
+	return _diffTriggers(current, config, prune)
+}
+
+func _diffTriggers(
+	current []*fc.Trigger,
+	config *common.TriggersConfig,
+	prune bool,
+) (*TriggerPlan, error) {
+	currentMap := map[string]*fc.Trigger{}
+	for _, trigger := range current {
+		currentMap[tea.StringValue(trigger.TriggerName)] = trigger
+	}
+	var plan TriggerPlan
+	var violations []string
+	declared := map[string]bool{}
+	for _, spec := range config.Triggers {
+		triggerConfig, err := _getTriggerConfig(spec)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %s", spec.Name, err))
+			continue
+		}
+		qualifier := spec.Qualifier
+		if qualifier == "" {
+			qualifier = "LATEST"
+		}
+		declared[spec.Name] = true
+		trigger, ok := currentMap[spec.Name]
+		if !ok {
+			plan.Create = append(plan.Create, &fc.CreateTriggerInput{
+				TriggerName:   tea.String(spec.Name),
+				TriggerType:   tea.String(spec.Type),
+				TriggerConfig: tea.String(triggerConfig),
+				Qualifier:     tea.String(qualifier),
+				Description:   tea.String(common.TRIGGER_DESCRIPTION),
+			})
+			continue
+		}
+		if tea.StringValue(trigger.TriggerType) != spec.Type {
+			violations = append(violations, fmt.Sprintf(
+				"%s: trigger type %s can not be changed to %s",
+				spec.Name, tea.StringValue(trigger.TriggerType), spec.Type))
+			continue
+		}
+		if !common.IsJSONSubset(triggerConfig, tea.StringValue(trigger.TriggerConfig)) ||
+			qualifier != tea.StringValue(trigger.Qualifier) ||
+			tea.StringValue(trigger.Description) != common.TRIGGER_DESCRIPTION {
+			plan.Update = append(plan.Update, TriggerUpdate{
+				TriggerName: spec.Name,
+				TriggerType: spec.Type,
+				Input: &fc.UpdateTriggerInput{
+					TriggerConfig: tea.String(triggerConfig),
+					Qualifier:     tea.String(qualifier),
+					Description:   tea.String(common.TRIGGER_DESCRIPTION),
+				},
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("invalid triggers:\n  - %s", strings.Join(violations, "\n  - "))
+	}
+	for _, trigger := range current {
+		if declared[tea.StringValue(trigger.TriggerName)] {
+			continue
+		}
+		if common.IsTriggerRemovable(tea.StringValue(trigger.Description), prune) {
+			plan.Delete = append(plan.Delete, trigger)
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, trigger)
+		}
+	}
+	return &plan, nil
+}
+
+func _logTriggerPlan(plan *TriggerPlan) {
+	common.LogTriggerChanges(plan.Changes())
+}
+
+func _applyTriggerPlan(client *fc.Client, functionName string, plan *TriggerPlan) error {
+	for _, trigger := range plan.Delete {
+		_, err := client.DeleteTrigger(&functionName, trigger.TriggerName)
+		if err != nil {
+			return fmt.Errorf("delete trigger %s: %s", tea.StringValue(trigger.TriggerName), err)
+		}
+	}
+	for _, input := range plan.Create {
+		_, err := client.CreateTrigger(&functionName, &fc.CreateTriggerRequest{Body: input})
+		if err != nil {
+			return fmt.Errorf("create trigger %s: %s", *input.TriggerName, err)
+		}
+	}
+	for _, update := range plan.Update {
+		triggerName := update.TriggerName
+		_, err := client.UpdateTrigger(
+			&functionName, &triggerName, &fc.UpdateTriggerRequest{Body: update.Input})
+		if err != nil {
+			return fmt.Errorf("update trigger %s: %s", triggerName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	fc "github.com/alibabacloud-go/fc-20230330/v4/client"
+
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+// ListTriggers响应的触发器列表，触发器配置是平台补全后的JSON
+const _testListTriggersResponse = `[
+	{
+		"triggerName": "daily",
+		"triggerType": "timer",
+		"triggerConfig": "{\"cronExpression\":\"0 0 2 * * *\",\"enable\":true,\"payload\":\"\"}",
+		"qualifier": "LATEST",
+		"description": "managed by ezfaas",
+		"triggerId": "8d5c3d2e-0b7a-4f5e-9a5e-3f2c1b0a9d8e",
+		"status": "Ready",
+		"createdTime": "2024-01-02T10:00:00Z",
+		"lastModifiedTime": "2024-01-02T10:00:00Z"
+	},
+	{
+		"triggerName": "http",
+		"triggerType": "http",
+		"triggerConfig": "{\"authType\":\"anonymous\",\"disableURLInternet\":false,\"methods\":[\"GET\",\"POST\",\"PUT\",\"DELETE\",\"HEAD\",\"PATCH\",\"OPTIONS\"]}",
+		"qualifier": "LATEST",
+		"description": "",
+		"triggerId": "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d",
+		"status": "Ready",
+		"createdTime": "2024-01-02T10:00:00Z",
+		"lastModifiedTime": "2024-01-02T10:00:00Z"
+	}
+]`
+
+func _loadTestTriggers(t *testing.T) []*fc.Trigger {
+	var triggers []*fc.Trigger
+	if err := json.Unmarshal([]byte(_testListTriggersResponse), &triggers); err != nil {
+		t.Fatal(err)
+	}
+	return triggers
+}
+
+func TestDiffTriggers(t *testing.T) {
+	daily := common.TriggerSpec{Name: "daily", Type: common.TRIGGER_TIMER, Cron: "0 0 2 * * *"}
+	http := common.TriggerSpec{Name: "http", Type: common.TRIGGER_HTTP}
+	cases := []struct {
+		name   string
+		specs  []common.TriggerSpec
+		prune  bool
+		expect []string
+	}{
+		{
+			name:   "unchanged",
+			specs:  []common.TriggerSpec{daily},
+			expect: []string{"keep http/http"},
+		},
+		{
+			name: "changed cron",
+			specs: []common.TriggerSpec{
+				{Name: "daily", Type: common.TRIGGER_TIMER, Cron: "0 0 3 * * *"},
+			},
+			expect: []string{"update timer/daily", "keep http/http"},
+		},
+		{
+			name:   "undeclared managed",
+			specs:  nil,
+			expect: []string{"delete timer/daily", "keep http/http"},
+		},
+		{
+			name:   "prune",
+			specs:  []common.TriggerSpec{daily},
+			prune:  true,
+			expect: []string{"delete http/http"},
+		},
+		// 声明的触发器不是ezfaas创建的，更新描述后由ezfaas管理
+		{
+			name:   "declared unmanaged",
+			specs:  []common.TriggerSpec{daily, http},
+			expect: []string{"update http/http"},
+		},
+		{
+			name:   "create",
+			specs:  []common.TriggerSpec{daily, {Name: "hourly", Type: common.TRIGGER_TIMER, Cron: "0 0 * * * *"}},
+			expect: []string{"create timer/hourly", "keep http/http"},
+		},
+	}
+	for _, c := range cases {
+		config := &common.TriggersConfig{Triggers: c.specs}
+		plan, err := _diffTriggers(_loadTestTriggers(t), config, c.prune)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		summary := common.SummaryTriggerChanges(plan.Changes())
+		if !reflect.DeepEqual(summary, c.expect) {
+			t.Errorf("%s: plan = %q, expect %q", c.name, summary, c.expect)
+		}
+	}
+}
+
+func TestDiffTriggersInvalid(t *testing.T) {
+	disabled := false
+	cases := []struct {
+		name string
+		spec common.TriggerSpec
+	}{
+		{"missing cron", common.TriggerSpec{Name: "daily", Type: common.TRIGGER_TIMER}},
+		{"unsupported type", common.TriggerSpec{Name: "oss", Type: "oss"}},
+		{"change type", common.TriggerSpec{Name: "daily", Type: common.TRIGGER_HTTP}},
+		{"disable http", common.TriggerSpec{Name: "http", Type: common.TRIGGER_HTTP, Enabled: &disabled}},
+	}
+	for _, c := range cases {
+		config := &common.TriggersConfig{Triggers: []common.TriggerSpec{c.spec}}
+		if _, err := _diffTriggers(_loadTestTriggers(t), config, false); err == nil {
+			t.Errorf("%s: expect error", c.name)
+		}
+	}
+}
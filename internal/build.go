@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/guyskk/ezfaas/internal/common"
@@ -42,15 +43,15 @@ type BaseBuildParams struct {
 type BuildParams struct {
 	BaseBuildParams
 	Repository string
+	BuildInfo  *BuildInfo // nil表示生成新的构建信息
 }
 
-type BuildResult struct {
+type BuildInfo struct {
 	BuildId  string
 	CommitId string
-	Image    string
 }
 
-func Build(p BuildParams) (*BuildResult, error) {
+func NewBuildInfo() BuildInfo {
 	var suffix string
 	commitId, err := common.GetCommitId()
 	if err != nil {
@@ -59,7 +60,38 @@ func Build(p BuildParams) (*BuildResult, error) {
 	} else {
 		suffix = commitId[:6]
 	}
-	buildId := GetBuildId(suffix)
+	return BuildInfo{
+		BuildId:  GetBuildId(suffix),
+		CommitId: commitId,
+	}
+}
+
+/* Get build info of existed build id, commit id is empty if not match */
+func GetExistedBuildInfo(buildId string) BuildInfo {
+	commitId, err := common.GetCommitId()
+	if err != nil || !strings.HasSuffix(buildId, "-"+commitId[:6]) {
+		commitId = ""
+	}
+	return BuildInfo{
+		BuildId:  buildId,
+		CommitId: commitId,
+	}
+}
+
+type BuildResult struct {
+	BuildId  string
+	CommitId string
+	Image    string
+}
+
+func Build(p BuildParams) (*BuildResult, error) {
+	var buildInfo BuildInfo
+	if p.BuildInfo != nil {
+		buildInfo = *p.BuildInfo
+	} else {
+		buildInfo = NewBuildInfo()
+	}
+	buildId, commitId := buildInfo.BuildId, buildInfo.CommitId
 	image := fmt.Sprintf("%s:%s", p.Repository, buildId)
 	imageList := []string{image}
 	imageList = append(imageList, p.ImageTagList...)
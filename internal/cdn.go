@@ -2,7 +2,10 @@ package internal
 
 import (
 	"log"
+	"strings"
+	"time"
 
+	"github.com/guyskk/ezfaas/internal/aliyun"
 	"github.com/guyskk/ezfaas/internal/common"
 	"github.com/guyskk/ezfaas/internal/tencent"
 )
@@ -15,5 +18,130 @@ func DoConfigCdnCacheTencent(params TencentCDNCacheConfigParams) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	common.LogPrettyJSON(output)
+	if output != nil {
+		common.LogPrettyJSON(output)
+	}
+}
+
+type CDNConfigParams struct {
+	Provider           string
+	Region             string
+	Domain             string
+	UsageLimit         string
+	RulesFile          string
+	UsageLimitOverride common.CDNUsageLimit
+	Export             string
+	Yes                bool
+	Wait               bool
+	WaitTimeout        time.Duration
+}
+
+func DoCdnConfig(params CDNConfigParams) {
+	switch params.Provider {
+	case PROVIDER_TENCENT:
+		DoConfigCdnCacheTencent(TencentCDNCacheConfigParams{
+			Region:             params.Region,
+			Domain:             params.Domain,
+			UsageLimit:         params.UsageLimit,
+			RulesFile:          params.RulesFile,
+			UsageLimitOverride: params.UsageLimitOverride,
+			Export:             params.Export,
+			Yes:                params.Yes,
+			Wait:               params.Wait,
+			WaitTimeout:        params.WaitTimeout,
+		})
+	case PROVIDER_ALIYUN:
+		if params.Export != "" || params.Wait {
+			log.Fatalf("--export and --wait are not supported by provider %s", params.Provider)
+		}
+		output, err := aliyun.UpdateCDNCacheConfig(aliyun.CDNCacheConfigParams{
+			Domain:             params.Domain,
+			UsageLimit:         params.UsageLimit,
+			RulesFile:          params.RulesFile,
+			UsageLimitOverride: params.UsageLimitOverride,
+			Yes:                params.Yes,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if output != nil {
+			common.LogPrettyJSON(output)
+		}
+	default:
+		log.Fatalf("invalid provider %q, expect %s or %s",
+			params.Provider, PROVIDER_TENCENT, PROVIDER_ALIYUN)
+	}
+}
+
+type CDNPurgeParams struct {
+	Domain    string
+	Paths     []string
+	Dirs      []string
+	FlushType string
+	Timeout   time.Duration
+}
+
+type CDNPrefetchParams struct {
+	Urls     []string
+	UrlsFile string
+	Timeout  time.Duration
+}
+
+type CDNCertParams struct {
+	Domains  []string
+	CertFile string
+	KeyFile  string
+}
+
+func _purgeCDNCache(params CDNPurgeParams) error {
+	log.Printf("[INFO] Purge CDN Domain=%s", params.Domain)
+	_, err := tencent.PurgeCDNCache(tencent.CDNPurgeParams{
+		Domain:    params.Domain,
+		Paths:     params.Paths,
+		Dirs:      params.Dirs,
+		FlushType: params.FlushType,
+		Timeout:   params.Timeout,
+	})
+	return err
+}
+
+func DoCdnPurge(params CDNPurgeParams) {
+	err := _purgeCDNCache(params)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func DoCdnPrefetch(params CDNPrefetchParams) {
+	urls := params.Urls
+	if params.UrlsFile != "" {
+		data, err := common.ReadUserFile(params.UrlsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+	}
+	_, err := tencent.PrefetchCDNCache(tencent.CDNPrefetchParams{
+		Urls:    urls,
+		Timeout: params.Timeout,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func DoCdnCert(params CDNCertParams) {
+	err := tencent.DeployCDNCertificate(tencent.CDNCertParams{
+		Domains:  params.Domains,
+		CertFile: params.CertFile,
+		KeyFile:  params.KeyFile,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
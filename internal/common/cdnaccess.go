@@ -0,0 +1,173 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+/*
+CDN access control and header rules in rules file, sections not present are
+not changed, example:
+
+	http2 = true
+
+	[referer]
+	enabled = true
+	type = "whitelist"
+	referers = ["example.com", "*.example.com"]
+	allow_empty = true
+
+	[ip_filter]
+	enabled = true
+	type = "blacklist"
+	filters = ["1.2.3.4", "10.0.0.0/8"]
+
+	[response_header]
+	enabled = true
+	[[response_header.rules]]
+	type = "all"
+	paths = ["*"]
+	mode = "set"
+	name = "Access-Control-Allow-Origin"
+	value = "*"
+
+	[compression]
+	enabled = true
+	[[compression.rules]]
+	type = "file"
+	paths = ["js", "css", "html"]
+	algorithms = ["gzip", "brotli"]
+	min_length = 256
+	max_length = 2097152
+*/
+const (
+	CDN_FILTER_WHITELIST string = "whitelist"
+	CDN_FILTER_BLACKLIST string = "blacklist"
+)
+
+const (
+	CDN_HEADER_ADD string = "add"
+	CDN_HEADER_SET string = "set"
+	CDN_HEADER_DEL string = "del"
+)
+
+const (
+	CDN_COMPRESSION_GZIP   string = "gzip"
+	CDN_COMPRESSION_BROTLI string = "brotli"
+)
+
+type CDNRefererRule struct {
+	Enabled    bool     `toml:"enabled" json:"enabled"`
+	Type       string   `toml:"type" json:"type"` // whitelist 或 blacklist
+	Referers   []string `toml:"referers" json:"referers"`
+	AllowEmpty bool     `toml:"allow_empty" json:"allow_empty"` // 是否允许空Referer
+}
+
+type CDNIpFilterRule struct {
+	Enabled bool     `toml:"enabled" json:"enabled"`
+	Type    string   `toml:"type" json:"type"`       // whitelist 或 blacklist
+	Filters []string `toml:"filters" json:"filters"` // IP或网段
+}
+
+type CDNHeaderRule struct {
+	Type  string   `toml:"type" json:"type"`
+	Paths []string `toml:"paths" json:"paths"`
+	Mode  string   `toml:"mode" json:"mode"` // add, set 或 del
+	Name  string   `toml:"name" json:"name"`
+	Value string   `toml:"value,omitempty" json:"value,omitempty"`
+}
+
+type CDNResponseHeader struct {
+	Enabled bool            `toml:"enabled" json:"enabled"`
+	Rules   []CDNHeaderRule `toml:"rules" json:"rules"`
+}
+
+type CDNCompressionRule struct {
+	Type       string   `toml:"type" json:"type"`
+	Paths      []string `toml:"paths" json:"paths"`
+	Algorithms []string `toml:"algorithms" json:"algorithms"`
+	MinLength  int64    `toml:"min_length" json:"min_length"` // 字节
+	MaxLength  int64    `toml:"max_length" json:"max_length"` // 字节
+}
+
+type CDNCompression struct {
+	Enabled bool                 `toml:"enabled" json:"enabled"`
+	Rules   []CDNCompressionRule `toml:"rules" json:"rules"`
+}
+
+func _checkCDNFilterType(filterType string) error {
+	switch filterType {
+	case CDN_FILTER_WHITELIST, CDN_FILTER_BLACKLIST:
+		return nil
+	}
+	return fmt.Errorf("type must be %s or %s", CDN_FILTER_WHITELIST, CDN_FILTER_BLACKLIST)
+}
+
+func _getCDNAccessViolations(rules CDNRules) []string {
+	var violations []string
+	if rules.Referer != nil && rules.Referer.Enabled {
+		if err := _checkCDNFilterType(rules.Referer.Type); err != nil {
+			violations = append(violations, fmt.Sprintf("referer: %s", err))
+		}
+		if len(rules.Referer.Referers) <= 0 {
+			violations = append(violations, "referer: referers is required")
+		}
+	}
+	if rules.IpFilter != nil && rules.IpFilter.Enabled {
+		if err := _checkCDNFilterType(rules.IpFilter.Type); err != nil {
+			violations = append(violations, fmt.Sprintf("ip_filter: %s", err))
+		}
+		if len(rules.IpFilter.Filters) <= 0 {
+			violations = append(violations, "ip_filter: filters is required")
+		}
+		for _, filter := range rules.IpFilter.Filters {
+			_, _, err := net.ParseCIDR(filter)
+			if err != nil && net.ParseIP(filter) == nil {
+				violations = append(violations, fmt.Sprintf(
+					"ip_filter: invalid ip or cidr %q", filter))
+			}
+		}
+	}
+	if rules.ResponseHeader != nil {
+		for i, rule := range rules.ResponseHeader.Rules {
+			prefix := fmt.Sprintf("response_header.rules[%d]", i)
+			if err := _checkCDNRulePaths(rule.Type, rule.Paths); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %s", prefix, err))
+			}
+			switch rule.Mode {
+			case CDN_HEADER_ADD, CDN_HEADER_SET, CDN_HEADER_DEL:
+			default:
+				violations = append(violations, fmt.Sprintf(
+					"%s: mode must be %s, %s or %s",
+					prefix, CDN_HEADER_ADD, CDN_HEADER_SET, CDN_HEADER_DEL))
+			}
+			if rule.Name == "" || strings.ContainsAny(rule.Name, " :") {
+				violations = append(violations, fmt.Sprintf("%s: invalid header name %q", prefix, rule.Name))
+			}
+		}
+	}
+	if rules.Compression != nil {
+		for i, rule := range rules.Compression.Rules {
+			prefix := fmt.Sprintf("compression.rules[%d]", i)
+			if err := _checkCDNRulePaths(rule.Type, rule.Paths); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %s", prefix, err))
+			}
+			if len(rule.Algorithms) <= 0 {
+				violations = append(violations, fmt.Sprintf("%s: algorithms is required", prefix))
+			}
+			for _, algorithm := range rule.Algorithms {
+				if algorithm != CDN_COMPRESSION_GZIP && algorithm != CDN_COMPRESSION_BROTLI {
+					violations = append(violations, fmt.Sprintf(
+						"%s: algorithm must be %s or %s",
+						prefix, CDN_COMPRESSION_GZIP, CDN_COMPRESSION_BROTLI))
+				}
+			}
+			if rule.MinLength < 0 || rule.MaxLength <= rule.MinLength {
+				violations = append(violations, fmt.Sprintf(
+					"%s: min_length must >= 0 and max_length must > min_length", prefix))
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,291 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+/*
+CDN rules file in TOML or JSON format, example:
+
+	[[cache]]
+	type = "directory"
+	paths = ["/api"]
+	no_cache = true
+
+	[[cache]]
+	type = "file"
+	paths = ["js", "css"]
+	ttl = 36000
+
+	[[max_age]]
+	type = "all"
+	paths = ["*"]
+	ttl = 30
+	follow_origin = true
+
+	[usage_limit]
+	ip_qps = 50
+	downstream_kbps = 1024
+	bandwidth_mbps = 10
+	counter_measure = "RETURN_404"
+*/
+const (
+	CDN_RULE_ALL       string = "all"
+	CDN_RULE_DIRECTORY string = "directory"
+	CDN_RULE_PATH      string = "path"
+	CDN_RULE_INDEX     string = "index"
+	CDN_RULE_FILE      string = "file"
+)
+
+type CDNCacheRule struct {
+	Type         string   `toml:"type" json:"type"`
+	Paths        []string `toml:"paths" json:"paths"`
+	TTL          int64    `toml:"ttl" json:"ttl"` // 秒
+	NoCache      bool     `toml:"no_cache,omitempty" json:"no_cache,omitempty"`
+	FollowOrigin bool     `toml:"follow_origin,omitempty" json:"follow_origin,omitempty"`
+}
+
+type CDNMaxAgeRule struct {
+	Type         string   `toml:"type" json:"type"`
+	Paths        []string `toml:"paths" json:"paths"`
+	TTL          int64    `toml:"ttl" json:"ttl"` // 秒
+	FollowOrigin bool     `toml:"follow_origin,omitempty" json:"follow_origin,omitempty"`
+}
+
+const (
+	// 超过带宽阈值后返回404
+	CDN_COUNTER_MEASURE_RETURN_404 string = "RETURN_404"
+	// 超过带宽阈值后将域名解析回源站
+	CDN_COUNTER_MEASURE_RESOLVE_DNS_TO_ORIGIN string = "RESOLVE_DNS_TO_ORIGIN"
+)
+
+/* Rate limit and usage capping, enabled by --usagelimit on */
+type CDNUsageLimit struct {
+	// 是否开启，为空表示不修改，--usagelimit参数优先
+	Enabled         *bool  `toml:"enabled,omitempty" json:"enabled,omitempty"`
+	IpQps           int64  `toml:"ip_qps" json:"ip_qps"`                     // 单IP每秒请求数
+	DownstreamKBps  int64  `toml:"downstream_kbps" json:"downstream_kbps"`   // 单连接下行限速
+	BandwidthMbps   uint64 `toml:"bandwidth_mbps" json:"bandwidth_mbps"`     // 带宽封顶阈值
+	CounterMeasure  string `toml:"counter_measure" json:"counter_measure"`   // 超过阈值后的处理方式
+	Cycle           uint64 `toml:"cycle" json:"cycle"`                       // 检测周期，分钟
+	UnblockTime     uint64 `toml:"unblock_time" json:"unblock_time"`         // 自动解封时间，分钟
+	AlertPercentage uint64 `toml:"alert_percentage" json:"alert_percentage"` // 告警阈值百分比
+}
+
+type CDNRules struct {
+	Cache      []CDNCacheRule  `toml:"cache" json:"cache"`
+	MaxAge     []CDNMaxAgeRule `toml:"max_age" json:"max_age"`
+	UsageLimit CDNUsageLimit   `toml:"usage_limit" json:"usage_limit"`
+	// 以下配置为空表示不修改
+	Http2          *bool              `toml:"http2,omitempty" json:"http2,omitempty"`
+	Referer        *CDNRefererRule    `toml:"referer,omitempty" json:"referer,omitempty"`
+	IpFilter       *CDNIpFilterRule   `toml:"ip_filter,omitempty" json:"ip_filter,omitempty"`
+	ResponseHeader *CDNResponseHeader `toml:"response_header,omitempty" json:"response_header,omitempty"`
+	Compression    *CDNCompression    `toml:"compression,omitempty" json:"compression,omitempty"`
+}
+
+func DefaultCDNUsageLimit() CDNUsageLimit {
+	return CDNUsageLimit{
+		IpQps:           50,
+		DownstreamKBps:  1024,
+		BandwidthMbps:   10,
+		CounterMeasure:  CDN_COUNTER_MEASURE_RETURN_404,
+		Cycle:           5,
+		UnblockTime:     60,
+		AlertPercentage: 50,
+	}
+}
+
+/* Override usage limit with non-zero fields */
+func MergeCDNUsageLimit(limit *CDNUsageLimit, override CDNUsageLimit) {
+	if override.IpQps != 0 {
+		limit.IpQps = override.IpQps
+	}
+	if override.DownstreamKBps != 0 {
+		limit.DownstreamKBps = override.DownstreamKBps
+	}
+	if override.BandwidthMbps != 0 {
+		limit.BandwidthMbps = override.BandwidthMbps
+	}
+	if override.CounterMeasure != "" {
+		limit.CounterMeasure = override.CounterMeasure
+	}
+	if override.Cycle != 0 {
+		limit.Cycle = override.Cycle
+	}
+	if override.UnblockTime != 0 {
+		limit.UnblockTime = override.UnblockTime
+	}
+	if override.AlertPercentage != 0 {
+		limit.AlertPercentage = override.AlertPercentage
+	}
+}
+
+func _getCDNUsageLimitViolations(limit CDNUsageLimit) []string {
+	var violations []string
+	if limit.IpQps <= 0 {
+		violations = append(violations, "usage_limit.ip_qps must > 0")
+	}
+	if limit.DownstreamKBps <= 0 {
+		violations = append(violations, "usage_limit.downstream_kbps must > 0")
+	}
+	if limit.BandwidthMbps <= 0 {
+		violations = append(violations, "usage_limit.bandwidth_mbps must > 0")
+	}
+	switch limit.CounterMeasure {
+	case CDN_COUNTER_MEASURE_RETURN_404, CDN_COUNTER_MEASURE_RESOLVE_DNS_TO_ORIGIN:
+	default:
+		violations = append(violations, fmt.Sprintf(
+			"usage_limit.counter_measure must be %s or %s",
+			CDN_COUNTER_MEASURE_RETURN_404, CDN_COUNTER_MEASURE_RESOLVE_DNS_TO_ORIGIN))
+	}
+	if limit.Cycle <= 0 {
+		violations = append(violations, "usage_limit.cycle must > 0")
+	}
+	if limit.UnblockTime <= 0 {
+		violations = append(violations, "usage_limit.unblock_time must > 0")
+	}
+	if limit.AlertPercentage <= 0 || limit.AlertPercentage > 100 {
+		violations = append(violations, "usage_limit.alert_percentage must in 1-100")
+	}
+	return violations
+}
+
+var _cdnStaticDirectories = []string{
+	"/js", "/css", "/fonts", "/imgs", "/img", "/libs", "/static", "/assets",
+}
+
+/* Default rules for single page application */
+func DefaultCDNRules() CDNRules {
+	var indexTTL int64 = 10
+	var staticTTL int64 = 10 * 60 * 60
+	var indexMaxAge int64 = 30
+	var staticMaxAge int64 = 10 * 24 * 60 * 60
+	return CDNRules{
+		Cache: []CDNCacheRule{
+			{Type: CDN_RULE_ALL, Paths: []string{"*"}, TTL: indexTTL},
+			{Type: CDN_RULE_DIRECTORY, Paths: []string{"/api"}, NoCache: true},
+			{Type: CDN_RULE_DIRECTORY, Paths: _cdnStaticDirectories, TTL: staticTTL},
+			{Type: CDN_RULE_PATH, Paths: []string{"/favicon.ico"}, TTL: staticTTL},
+			{Type: CDN_RULE_PATH, Paths: []string{"/manifest.json", "/service-worker.js"}, TTL: indexTTL},
+			{Type: CDN_RULE_INDEX, Paths: []string{"/"}, TTL: indexTTL},
+		},
+		MaxAge: []CDNMaxAgeRule{
+			{Type: CDN_RULE_ALL, Paths: []string{"*"}, TTL: indexMaxAge, FollowOrigin: true},
+			{Type: CDN_RULE_DIRECTORY, Paths: []string{"/api"}, TTL: 0, FollowOrigin: true},
+			{Type: CDN_RULE_DIRECTORY, Paths: _cdnStaticDirectories, TTL: staticMaxAge},
+			{Type: CDN_RULE_PATH, Paths: []string{"/favicon.ico"}, TTL: staticMaxAge},
+			{Type: CDN_RULE_PATH, Paths: []string{"/manifest.json", "/service-worker.js"}, TTL: indexMaxAge},
+			{Type: CDN_RULE_INDEX, Paths: []string{"/"}, TTL: indexMaxAge},
+		},
+		UsageLimit: DefaultCDNUsageLimit(),
+	}
+}
+
+func _checkCDNRulePaths(ruleType string, paths []string) error {
+	if len(paths) <= 0 {
+		return fmt.Errorf("paths is required")
+	}
+	for _, path := range paths {
+		switch ruleType {
+		case CDN_RULE_ALL:
+			if path != "*" {
+				return fmt.Errorf("path of all rule must be *")
+			}
+		case CDN_RULE_INDEX:
+			if path != "/" {
+				return fmt.Errorf("path of index rule must be /")
+			}
+		case CDN_RULE_DIRECTORY, CDN_RULE_PATH:
+			if !strings.HasPrefix(path, "/") {
+				return fmt.Errorf("path %q must start with /", path)
+			}
+		case CDN_RULE_FILE:
+			if path == "" || strings.ContainsAny(path, "/.") {
+				return fmt.Errorf("file extension %q should not contain / or .", path)
+			}
+		default:
+			return fmt.Errorf("invalid rule type %q", ruleType)
+		}
+	}
+	return nil
+}
+
+/* Validate rules, report all invalid rules at once */
+func ValidateCDNRules(rules CDNRules) error {
+	var violations []string
+	for i, rule := range rules.Cache {
+		if err := _checkCDNRulePaths(rule.Type, rule.Paths); err != nil {
+			violations = append(violations, fmt.Sprintf("cache[%d]: %s", i, err))
+		}
+		if rule.TTL < 0 {
+			violations = append(violations, fmt.Sprintf("cache[%d]: ttl must >= 0", i))
+		}
+		if rule.NoCache && rule.FollowOrigin {
+			violations = append(violations, fmt.Sprintf(
+				"cache[%d]: no_cache and follow_origin are exclusive", i))
+		}
+	}
+	for i, rule := range rules.MaxAge {
+		if err := _checkCDNRulePaths(rule.Type, rule.Paths); err != nil {
+			violations = append(violations, fmt.Sprintf("max_age[%d]: %s", i, err))
+		}
+		if rule.TTL < 0 {
+			violations = append(violations, fmt.Sprintf("max_age[%d]: ttl must >= 0", i))
+		}
+	}
+	violations = append(violations, _getCDNUsageLimitViolations(rules.UsageLimit)...)
+	violations = append(violations, _getCDNAccessViolations(rules)...)
+	if len(violations) > 0 {
+		return fmt.Errorf("invalid cdn rules:\n  - %s", strings.Join(violations, "\n  - "))
+	}
+	return nil
+}
+
+/*
+Load rules from TOML or JSON file, use default rules if filepath is empty.
+The rules should be validated after overrides are applied.
+*/
+func LoadCDNRules(filepath string) (*CDNRules, error) {
+	if filepath == "" {
+		rules := DefaultCDNRules()
+		return &rules, nil
+	}
+	data, err := ReadUserFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	// 未配置的用量封顶参数使用默认值
+	rules := CDNRules{UsageLimit: DefaultCDNUsageLimit()}
+	if strings.HasSuffix(strings.ToLower(filepath), ".json") {
+		err = json.Unmarshal(data, &rules)
+	} else {
+		err = toml.Unmarshal(data, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath, err)
+	}
+	return &rules, nil
+}
+
+/* Encode rules to TOML or JSON by file extension */
+func EncodeCDNRules(rules CDNRules, filepath string) (string, error) {
+	if strings.HasSuffix(strings.ToLower(filepath), ".json") {
+		data, err := json.MarshalIndent(rules, "", "    ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+	var buffer bytes.Buffer
+	err := toml.NewEncoder(&buffer).Encode(rules)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/* TLS certificate loaded from PEM files, eg: fullchain.pem and privkey.pem of certbot */
+type Certificate struct {
+	CertPEM string
+	KeyPEM  string
+	Leaf    *x509.Certificate
+}
+
+/* Load certificate and private key, the key must match the certificate */
+func LoadCertificate(certFile string, keyFile string) (*Certificate, error) {
+	certPEM, err := ReadUserFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ReadUserFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", certFile, err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", certFile, err)
+	}
+	return &Certificate{
+		CertPEM: string(certPEM),
+		KeyPEM:  string(keyPEM),
+		Leaf:    leaf,
+	}, nil
+}
+
+func (c *Certificate) String() string {
+	return fmt.Sprintf("subject=%s sans=%s expire=%s",
+		c.Leaf.Subject.CommonName,
+		strings.Join(c.Leaf.DNSNames, ","),
+		c.Leaf.NotAfter.Format(time.RFC3339))
+}
+
+/* Check certificate is valid now and SANs match the domain */
+func CheckCertificate(cert *Certificate, domain string) error {
+	now := time.Now()
+	if now.After(cert.Leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", cert.Leaf.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(cert.Leaf.NotBefore) {
+		return fmt.Errorf("certificate not valid before %s", cert.Leaf.NotBefore.Format(time.RFC3339))
+	}
+	if err := cert.Leaf.VerifyHostname(domain); err != nil {
+		return fmt.Errorf("certificate not match domain %s, sans=%s",
+			domain, strings.Join(cert.Leaf.DNSNames, ","))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* 生成自签名证书，返回证书和私钥的PEM */
+func _generateTestCertificate(t *testing.T, dnsNames []string, notBefore, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return certPEM, keyPEM
+}
+
+func _writeTestFile(t *testing.T, dir string, name string, data []byte) string {
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	certPEM, keyPEM := _generateTestCertificate(t, []string{"example.com"}, now.Add(-time.Hour), now.Add(time.Hour))
+	_, otherKeyPEM := _generateTestCertificate(t, []string{"example.com"}, now.Add(-time.Hour), now.Add(time.Hour))
+	certFile := _writeTestFile(t, dir, "cert.pem", certPEM)
+	keyFile := _writeTestFile(t, dir, "key.pem", keyPEM)
+	otherKeyFile := _writeTestFile(t, dir, "other-key.pem", otherKeyPEM)
+	cases := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		err      string // 错误信息包含的内容，为空表示成功
+	}{
+		{"valid", certFile, keyFile, ""},
+		{"key not match", certFile, otherKeyFile, "private key does not match"},
+		{"key as cert", keyFile, keyFile, "key.pem"},
+		{"missing key", certFile, filepath.Join(dir, "missing.pem"), "missing.pem"},
+	}
+	for _, c := range cases {
+		cert, err := LoadCertificate(c.certFile, c.keyFile)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expect error %q, got %v", c.name, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if cert.CertPEM != string(certPEM) || cert.KeyPEM != string(keyPEM) {
+			t.Errorf("%s: PEM not match", c.name)
+		}
+		if cert.Leaf.Subject.CommonName != "example.com" {
+			t.Errorf("%s: subject=%s", c.name, cert.Leaf.Subject.CommonName)
+		}
+	}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	cases := []struct {
+		name      string
+		dnsNames  []string
+		notBefore time.Time
+		notAfter  time.Time
+		domain    string
+		err       string // 错误信息包含的内容，为空表示成功
+	}{
+		{"valid", []string{"example.com"}, now.Add(-time.Hour), now.Add(time.Hour), "example.com", ""},
+		{"second san", []string{"example.com", "api.example.com"},
+			now.Add(-time.Hour), now.Add(time.Hour), "api.example.com", ""},
+		{"wildcard san", []string{"*.example.com"}, now.Add(-time.Hour), now.Add(time.Hour), "api.example.com", ""},
+		{"wildcard not match root", []string{"*.example.com"},
+			now.Add(-time.Hour), now.Add(time.Hour), "example.com", "not match domain"},
+		{"domain not match", []string{"example.com"},
+			now.Add(-time.Hour), now.Add(time.Hour), "example.org", "not match domain"},
+		{"expired", []string{"example.com"}, now.Add(-2 * time.Hour), now.Add(-time.Hour), "example.com", "expired"},
+		{"not yet valid", []string{"example.com"},
+			now.Add(time.Hour), now.Add(2 * time.Hour), "example.com", "not valid before"},
+	}
+	for i, c := range cases {
+		certPEM, keyPEM := _generateTestCertificate(t, c.dnsNames, c.notBefore, c.notAfter)
+		prefix := string(rune('a' + i))
+		certFile := _writeTestFile(t, dir, prefix+"-cert.pem", certPEM)
+		keyFile := _writeTestFile(t, dir, prefix+"-key.pem", keyPEM)
+		cert, err := LoadCertificate(certFile, keyFile)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		err = CheckCertificate(cert, c.domain)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: %s", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expect error %q, got %v", c.name, c.err, err)
+		}
+	}
+}
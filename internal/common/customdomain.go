@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+/*
+Custom domains file declares domains and routes to functions, example:
+
+	[[custom_domains]]
+	domain = "api.example.com"
+	protocol = "HTTP,HTTPS"
+	cert = "/etc/letsencrypt/live/api.example.com/fullchain.pem"
+	key = "/etc/letsencrypt/live/api.example.com/privkey.pem"
+
+	[[custom_domains.routes]]
+	path = "/api/*"
+	function = "my-function"
+	qualifier = "LATEST"
+	methods = ["GET", "POST"]
+*/
+type RouteSpec struct {
+	Path      string   `toml:"path"`
+	Function  string   `toml:"function"`
+	Qualifier string   `toml:"qualifier"`
+	Methods   []string `toml:"methods"` // 为空表示允许所有方法
+}
+
+type CustomDomainSpec struct {
+	Domain   string      `toml:"domain"`
+	Protocol string      `toml:"protocol"` // HTTP, HTTPS 或 HTTP,HTTPS
+	CertFile string      `toml:"cert"`     // 证书PEM文件，HTTPS必填
+	KeyFile  string      `toml:"key"`      // 私钥PEM文件，HTTPS必填
+	Routes   []RouteSpec `toml:"routes"`
+}
+
+func (d CustomDomainSpec) IsHTTPS() bool {
+	return strings.Contains(strings.ToUpper(d.Protocol), "HTTPS")
+}
+
+type CustomDomainsConfig struct {
+	CustomDomains []CustomDomainSpec `toml:"custom_domains"`
+}
+
+func LoadCustomDomains(filepath string) (*CustomDomainsConfig, error) {
+	data, err := ReadUserFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	var config CustomDomainsConfig
+	err = toml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath, err)
+	}
+	var violations []string
+	domains := map[string]bool{}
+	for i, domain := range config.CustomDomains {
+		prefix := fmt.Sprintf("custom_domains[%d]", i)
+		if domain.Domain == "" {
+			violations = append(violations, prefix+": domain is required")
+		}
+		if domains[domain.Domain] {
+			violations = append(violations, fmt.Sprintf("%s: duplicated %s", prefix, domain.Domain))
+		}
+		domains[domain.Domain] = true
+		switch strings.ToUpper(domain.Protocol) {
+		case "HTTP", "HTTPS", "HTTP,HTTPS":
+		default:
+			violations = append(violations, prefix+": protocol must be HTTP, HTTPS or HTTP,HTTPS")
+		}
+		if domain.IsHTTPS() && (domain.CertFile == "" || domain.KeyFile == "") {
+			violations = append(violations, prefix+": cert and key are required for HTTPS")
+		}
+		for j, route := range domain.Routes {
+			if !strings.HasPrefix(route.Path, "/") {
+				violations = append(violations, fmt.Sprintf(
+					"%s.routes[%d]: path must start with /", prefix, j))
+			}
+			if route.Function == "" {
+				violations = append(violations, fmt.Sprintf(
+					"%s.routes[%d]: function is required", prefix, j))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("%s: invalid custom domains:\n  - %s",
+			filepath, strings.Join(violations, "\n  - "))
+	}
+	return &config, nil
+}
@@ -0,0 +1,51 @@
+package common
+
+/* Line diff by longest common subsequence, lines are prefixed with "  ", "- " or "+ " */
+func DiffLines(oldLines []string, newLines []string) []string {
+	m, n := len(oldLines), len(newLines)
+	lcs := make([][]int, m+1)
+	for i := range lcs {
+		lcs[i] = make([]int, n+1)
+	}
+	for i := m - 1; i >= 0; i-- {
+		for j := n - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var result []string
+	i, j := 0, 0
+	for i < m && j < n {
+		if oldLines[i] == newLines[j] {
+			result = append(result, "  "+oldLines[i])
+			i, j = i+1, j+1
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			result = append(result, "- "+oldLines[i])
+			i += 1
+		} else {
+			result = append(result, "+ "+newLines[j])
+			j += 1
+		}
+	}
+	for ; i < m; i++ {
+		result = append(result, "- "+oldLines[i])
+	}
+	for ; j < n; j++ {
+		result = append(result, "+ "+newLines[j])
+	}
+	return result
+}
+
+func HasDiff(diffLines []string) bool {
+	for _, line := range diffLines {
+		if line[0] != ' ' {
+			return true
+		}
+	}
+	return false
+}
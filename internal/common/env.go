@@ -0,0 +1,142 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	// 使用envfile和--env的变量替换函数全部环境变量
+	ENV_MODE_REPLACE string = "replace"
+	// 在函数当前环境变量基础上合并envfile和--env的变量
+	ENV_MODE_MERGE string = "merge"
+	// 只修改--env和--unset-env指定的变量，不使用envfile
+	ENV_MODE_PATCH string = "patch"
+)
+
+type EnvUpdate struct {
+	Mode        string
+	Variables   map[string]string // envfile中的变量
+	SetEnv      map[string]string // --env指定的变量
+	UnsetList   []string          // KEY
+	SecretNames []string          // 值来自密钥引用的变量，不能输出到日志
+	Schema      *EnvSchema        // nil表示不检查
+}
+
+func IsValidEnvMode(mode string) bool {
+	switch mode {
+	case ENV_MODE_REPLACE, ENV_MODE_MERGE, ENV_MODE_PATCH:
+		return true
+	}
+	return false
+}
+
+/*
+Default env mode is replace with envfile, otherwise patch. Replace without
+envfile would delete all variables not specified by --env.
+*/
+func GetEnvMode(mode string, hasEnvfile bool) string {
+	if mode != "" {
+		return mode
+	}
+	if hasEnvfile {
+		return ENV_MODE_REPLACE
+	}
+	return ENV_MODE_PATCH
+}
+
+/* Parse KEY=VALUE list to map */
+func ParseEnvAssignments(list []string) (map[string]string, error) {
+	env := map[string]string{}
+	for _, item := range list {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env %q, expect KEY=VALUE", item)
+		}
+		env[parts[0]] = parts[1]
+	}
+	return env, nil
+}
+
+func SortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+Compute function environment variables from current variables and update,
+return the new variables and the deleted variable names.
+*/
+func ResolveEnv(
+	update EnvUpdate,
+	current map[string]string,
+) (map[string]string, []string, error) {
+	if !IsValidEnvMode(update.Mode) {
+		return nil, nil, fmt.Errorf("invalid env mode %q", update.Mode)
+	}
+	if update.Mode == ENV_MODE_REPLACE && update.Variables == nil {
+		return nil, nil, fmt.Errorf(
+			"env mode %s requires envfile, use %s or %s to keep other variables",
+			ENV_MODE_REPLACE, ENV_MODE_MERGE, ENV_MODE_PATCH)
+	}
+	env := map[string]string{}
+	if update.Mode != ENV_MODE_REPLACE {
+		for k, v := range current {
+			env[k] = v
+		}
+	}
+	if update.Mode != ENV_MODE_PATCH {
+		for k, v := range update.Variables {
+			env[k] = v
+		}
+	}
+	for k, v := range update.SetEnv {
+		env[k] = v
+	}
+	for _, k := range update.UnsetList {
+		delete(env, k)
+	}
+	for k, v := range GetEnvSchemaDefaults(update.Schema) {
+		if _, ok := env[k]; !ok {
+			env[k] = v
+		}
+	}
+	if err := CheckEnvSchema(update.Schema, env, true); err != nil {
+		return nil, nil, err
+	}
+	var deleted []string
+	for _, k := range SortedKeys(current) {
+		if _, ok := env[k]; !ok {
+			deleted = append(deleted, k)
+		}
+	}
+	return env, deleted, nil
+}
+
+func IsSecretEnv(update *EnvUpdate, name string) bool {
+	if update == nil {
+		return false
+	}
+	for _, secretName := range update.SecretNames {
+		if secretName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func LogDeletedEnv(mode string, deleted []string) {
+	if len(deleted) <= 0 {
+		return
+	}
+	log.Printf(
+		"[WARN] EnvMode=%s will delete variables: %s",
+		mode, strings.Join(deleted, ", "),
+	)
+}
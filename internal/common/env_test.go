@@ -0,0 +1,96 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetEnvMode(t *testing.T) {
+	cases := []struct {
+		mode       string
+		hasEnvfile bool
+		expect     string
+	}{
+		{"", true, ENV_MODE_REPLACE},
+		{"", false, ENV_MODE_PATCH},
+		{ENV_MODE_MERGE, true, ENV_MODE_MERGE},
+		{ENV_MODE_REPLACE, false, ENV_MODE_REPLACE},
+	}
+	for _, c := range cases {
+		mode := GetEnvMode(c.mode, c.hasEnvfile)
+		if mode != c.expect {
+			t.Errorf("GetEnvMode(%q, %v) = %q, expect %q", c.mode, c.hasEnvfile, mode, c.expect)
+		}
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	current := map[string]string{"A": "1", "B": "2", "C": "3"}
+	cases := []struct {
+		name    string
+		update  EnvUpdate
+		expect  map[string]string
+		deleted []string
+	}{
+		{
+			name: "env only keeps other variables",
+			update: EnvUpdate{
+				Mode:   GetEnvMode("", false),
+				SetEnv: map[string]string{"A": "x"},
+			},
+			expect: map[string]string{"A": "x", "B": "2", "C": "3"},
+		},
+		{
+			name: "patch unset",
+			update: EnvUpdate{
+				Mode:      ENV_MODE_PATCH,
+				UnsetList: []string{"B"},
+			},
+			expect:  map[string]string{"A": "1", "C": "3"},
+			deleted: []string{"B"},
+		},
+		{
+			name: "merge envfile",
+			update: EnvUpdate{
+				Mode:      ENV_MODE_MERGE,
+				Variables: map[string]string{"B": "y", "D": "4"},
+				SetEnv:    map[string]string{"D": "z"},
+			},
+			expect: map[string]string{"A": "1", "B": "y", "C": "3", "D": "z"},
+		},
+		{
+			name: "replace envfile",
+			update: EnvUpdate{
+				Mode:      ENV_MODE_REPLACE,
+				Variables: map[string]string{"B": "y"},
+				SetEnv:    map[string]string{"D": "4"},
+			},
+			expect:  map[string]string{"B": "y", "D": "4"},
+			deleted: []string{"A", "C"},
+		},
+	}
+	for _, c := range cases {
+		env, deleted, err := ResolveEnv(c.update, current)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(env, c.expect) {
+			t.Errorf("%s: env = %v, expect %v", c.name, env, c.expect)
+		}
+		if !reflect.DeepEqual(deleted, c.deleted) {
+			t.Errorf("%s: deleted = %v, expect %v", c.name, deleted, c.deleted)
+		}
+	}
+}
+
+func TestResolveEnvReplaceWithoutEnvfile(t *testing.T) {
+	update := EnvUpdate{
+		Mode:   ENV_MODE_REPLACE,
+		SetEnv: map[string]string{"A": "x"},
+	}
+	_, _, err := ResolveEnv(update, map[string]string{"A": "1", "B": "2"})
+	if err == nil {
+		t.Error("expect error for replace without envfile")
+	}
+}
@@ -0,0 +1,286 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+/*
+Encrypted envfile is a normal envfile with encrypted values:
+
+	# ezfaas-recipient: ezfaas-pk-xxx
+	DB_PASS=ENC[v1:xxx]
+
+Each value is encrypted with an ephemeral X25519 key agreement to the
+recipient public key, HKDF-SHA256 and ChaCha20-Poly1305, the variable name
+is used as additional data. Variable names and comments are kept plaintext.
+*/
+const (
+	ENV_KEY_ENV          string = "EZFAAS_ENV_KEY"
+	DEFAULT_ENV_KEY_FILE string = "~/.config/ezfaas_env.key"
+)
+
+const (
+	envPublicKeyPrefix  = "ezfaas-pk-"
+	envSecretKeyPrefix  = "EZFAAS-SK-"
+	envRecipientHeader  = "# ezfaas-recipient: "
+	envEncryptedPrefix  = "ENC[v1:"
+	envEncryptedSuffix  = "]"
+	envEncryptHKDFLabel = "ezfaas-envfile-v1"
+)
+
+var envKeyEncoding = base64.RawURLEncoding
+
+type EnvKey struct {
+	SecretKey []byte
+	PublicKey []byte
+}
+
+func _newEnvKey(secretKey []byte) (*EnvKey, error) {
+	publicKey, err := curve25519.X25519(secretKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	return &EnvKey{SecretKey: secretKey, PublicKey: publicKey}, nil
+}
+
+func GenerateEnvKey() (*EnvKey, error) {
+	secretKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(secretKey); err != nil {
+		return nil, err
+	}
+	return _newEnvKey(secretKey)
+}
+
+func (k *EnvKey) SecretKeyString() string {
+	return envSecretKeyPrefix + envKeyEncoding.EncodeToString(k.SecretKey)
+}
+
+func (k *EnvKey) PublicKeyString() string {
+	return envPublicKeyPrefix + envKeyEncoding.EncodeToString(k.PublicKey)
+}
+
+func ParseEnvSecretKey(text string) (*EnvKey, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, envSecretKeyPrefix) {
+		return nil, fmt.Errorf("invalid env secret key")
+	}
+	secretKey, err := envKeyEncoding.DecodeString(
+		strings.TrimPrefix(text, envSecretKeyPrefix))
+	if err != nil || len(secretKey) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("invalid env secret key")
+	}
+	return _newEnvKey(secretKey)
+}
+
+func ParseEnvPublicKey(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, envPublicKeyPrefix) {
+		return nil, fmt.Errorf("invalid env public key %q", text)
+	}
+	publicKey, err := envKeyEncoding.DecodeString(
+		strings.TrimPrefix(text, envPublicKeyPrefix))
+	if err != nil || len(publicKey) != curve25519.PointSize {
+		return nil, fmt.Errorf("invalid env public key %q", text)
+	}
+	return publicKey, nil
+}
+
+/*
+Load env secret key, from keyfile if specified, otherwise from
+EZFAAS_ENV_KEY environment variable or the default keyfile.
+*/
+func LoadEnvKey(keyfile string) (*EnvKey, error) {
+	if keyfile == "" {
+		if text, ok := os.LookupEnv(ENV_KEY_ENV); ok {
+			return ParseEnvSecretKey(text)
+		}
+		keyfile = DEFAULT_ENV_KEY_FILE
+	}
+	data, err := ReadUserFile(keyfile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEnvSecretKey(string(data))
+}
+
+func _deriveEnvCipherKey(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	reader := hkdf.New(sha256.New, shared, salt, []byte(envEncryptHKDFLabel))
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func _encryptEnvValue(recipient []byte, name string, value string) (string, error) {
+	ephemeral, err := GenerateEnvKey()
+	if err != nil {
+		return "", err
+	}
+	shared, err := curve25519.X25519(ephemeral.SecretKey, recipient)
+	if err != nil {
+		return "", err
+	}
+	key, err := _deriveEnvCipherKey(shared, ephemeral.PublicKey, recipient)
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return "", err
+	}
+	// 每个值使用不同的临时密钥，nonce可以固定为0
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	sealed := aead.Seal(ephemeral.PublicKey, nonce, []byte(value), []byte(name))
+	return envEncryptedPrefix + envKeyEncoding.EncodeToString(sealed) + envEncryptedSuffix, nil
+}
+
+func _decryptEnvValue(envKey *EnvKey, name string, value string) (string, error) {
+	encoded := strings.TrimSuffix(strings.TrimPrefix(value, envEncryptedPrefix), envEncryptedSuffix)
+	sealed, err := envKeyEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < curve25519.PointSize {
+		return "", fmt.Errorf("invalid encrypted value of %s", name)
+	}
+	ephemeral, ciphertext := sealed[:curve25519.PointSize], sealed[curve25519.PointSize:]
+	shared, err := curve25519.X25519(envKey.SecretKey, ephemeral)
+	if err != nil {
+		return "", err
+	}
+	key, err := _deriveEnvCipherKey(shared, ephemeral, envKey.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("decrypt value of %s failed, wrong key?", name)
+	}
+	return string(plaintext), nil
+}
+
+func _isEncryptedEnvValue(value string) bool {
+	return strings.HasPrefix(value, envEncryptedPrefix) &&
+		strings.HasSuffix(value, envEncryptedSuffix)
+}
+
+/* Split envfile line to name and raw value, ok is false for comment or blank line */
+func _splitEnvLine(line string) (prefix string, name string, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", "", false
+	}
+	index := strings.IndexAny(line, "=:")
+	if index < 0 {
+		return "", "", "", false
+	}
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[:index]), "export "))
+	return line[:index+1], name, strings.TrimSpace(line[index+1:]), true
+}
+
+/* Split envfile to lines, continuation lines of quoted value are joined */
+func _splitEnvLines(content string) []string {
+	var result []string
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		_, _, value, ok := _splitEnvLine(line)
+		if ok && value != "" && (value[0] == '\'' || value[0] == '"') {
+			for _findClosingQuote(value, value[0]) < 0 && i+1 < len(lines) {
+				i += 1
+				line += "\n" + lines[i]
+				value += "\n" + lines[i]
+			}
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+/* Get recipient public key from encrypted envfile header */
+func GetEnvfileRecipient(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, envRecipientHeader) {
+			return strings.TrimSpace(strings.TrimPrefix(line, envRecipientHeader))
+		}
+	}
+	return ""
+}
+
+func IsEncryptedEnvfile(content string) bool {
+	for _, line := range _splitEnvLines(content) {
+		_, _, value, ok := _splitEnvLine(line)
+		if ok && _isEncryptedEnvValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Encrypt plaintext values of envfile, encrypted values are kept. Changing
+recipient of encrypted envfile is refused, otherwise values would be
+encrypted with different keys.
+*/
+func EncryptEnvfile(content string, recipient string) (string, error) {
+	recipientKey, err := ParseEnvPublicKey(recipient)
+	if err != nil {
+		return "", err
+	}
+	current := GetEnvfileRecipient(content)
+	if current != "" && current != recipient && IsEncryptedEnvfile(content) {
+		return "", fmt.Errorf(
+			"envfile is encrypted for %s, decrypt it before changing recipient", current)
+	}
+	lines := _splitEnvLines(content)
+	if current == "" {
+		lines = append([]string{envRecipientHeader + recipient}, lines...)
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, envRecipientHeader) {
+			lines[i] = envRecipientHeader + recipient
+			continue
+		}
+		prefix, name, value, ok := _splitEnvLine(line)
+		if !ok || value == "" || _isEncryptedEnvValue(value) {
+			continue
+		}
+		encrypted, err := _encryptEnvValue(recipientKey, name, value)
+		if err != nil {
+			return "", err
+		}
+		lines[i] = prefix + encrypted
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+/* Decrypt encrypted values of envfile, the recipient header is kept */
+func DecryptEnvfile(content string, envKey *EnvKey) (string, error) {
+	lines := _splitEnvLines(content)
+	for i, line := range lines {
+		prefix, name, value, ok := _splitEnvLine(line)
+		if !ok || !_isEncryptedEnvValue(value) {
+			continue
+		}
+		decrypted, err := _decryptEnvValue(envKey, name, value)
+		if err != nil {
+			return "", err
+		}
+		lines[i] = prefix + decrypted
+	}
+	return strings.Join(lines, "\n"), nil
+}
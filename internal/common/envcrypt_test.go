@@ -0,0 +1,86 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const _testEnvfile = `# database
+DB_HOST=localhost
+DB_PASS="p@ss # word" # comment
+export API_KEY: 'abc'
+CERT="line1
+line2"
+EMPTY=
+`
+
+func TestEnvfileEncryptRoundTrip(t *testing.T) {
+	envKey, err := GenerateEnvKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := EncryptEnvfile(_testEnvfile, envKey.PublicKeyString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncryptedEnvfile(encrypted) || IsEncryptedEnvfile(_testEnvfile) {
+		t.Error("IsEncryptedEnvfile mismatch")
+	}
+	if GetEnvfileRecipient(encrypted) != envKey.PublicKeyString() {
+		t.Error("recipient header missing")
+	}
+	for _, secret := range []string{"localhost", "p@ss", "abc", "line2"} {
+		if strings.Contains(encrypted, secret) {
+			t.Errorf("encrypted envfile contains %q", secret)
+		}
+	}
+	decrypted, err := DecryptEnvfile(encrypted, envKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(key string) (string, bool) { return "", false }
+	expect, err := ParseEnvfile(_testEnvfile, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := ParseEnvfile(decrypted, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env, expect) {
+		t.Errorf("decrypted env = %q, expect %q", env, expect)
+	}
+	// 已加密的值保持不变
+	again, err := EncryptEnvfile(encrypted, envKey.PublicKeyString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != encrypted {
+		t.Error("encrypt again changed encrypted values")
+	}
+}
+
+func TestEnvfileDecryptWrongKey(t *testing.T) {
+	envKey, _ := GenerateEnvKey()
+	otherKey, _ := GenerateEnvKey()
+	encrypted, err := EncryptEnvfile("A=1", envKey.PublicKeyString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptEnvfile(encrypted, otherKey); err == nil {
+		t.Error("expect error for wrong key")
+	}
+}
+
+func TestEnvfileEncryptChangeRecipient(t *testing.T) {
+	envKey, _ := GenerateEnvKey()
+	otherKey, _ := GenerateEnvKey()
+	encrypted, err := EncryptEnvfile("A=1", envKey.PublicKeyString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncryptEnvfile(encrypted+"\nB=2", otherKey.PublicKeyString()); err == nil {
+		t.Error("expect error for changing recipient")
+	}
+}
@@ -0,0 +1,138 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// 与godotenv一致的转义和变量引用规则
+var (
+	envEscapeRegex   = regexp.MustCompile(`\\.`)
+	envUnescapeRegex = regexp.MustCompile(`\\([^$])`)
+	envExpandRegex   = regexp.MustCompile(`(\\)?(\$)(\()?\{?([A-Z0-9_]+)?\}?`)
+)
+
+func IsValidEnvName(name string) bool {
+	return envNameRegex.MatchString(name)
+}
+
+type EnvLookup func(key string) (string, bool)
+
+/*
+Expand $VAR and ${VAR} references in value, \$ is kept as literal $,
+undefined variable is expanded to empty string, the same as godotenv.
+*/
+func _expandEnvValue(value string, lookup EnvLookup) string {
+	return envExpandRegex.ReplaceAllStringFunc(value, func(s string) string {
+		submatch := envExpandRegex.FindStringSubmatch(s)
+		if submatch[1] == "\\" {
+			return s[1:]
+		}
+		if submatch[3] == "(" || submatch[4] == "" {
+			return s
+		}
+		v, _ := lookup(submatch[4])
+		return v
+	})
+}
+
+/* Process escape sequences of double quoted value, \n and \r are expanded */
+func _unescapeEnvValue(value string) string {
+	value = envEscapeRegex.ReplaceAllStringFunc(value, func(match string) string {
+		switch match[1] {
+		case 'n':
+			return "\n"
+		case 'r':
+			return "\r"
+		}
+		return match
+	})
+	return envUnescapeRegex.ReplaceAllString(value, "$1")
+}
+
+/* Index of the closing quote, backslash escapes quote in double quoted value */
+func _findClosingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '\\' && quote == '"' {
+			i += 1
+			continue
+		}
+		if value[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+/* Split KEY=VALUE or yaml style KEY: VALUE, export prefix is ignored */
+func _splitEnvAssignment(line string) (string, string, error) {
+	index := strings.IndexAny(line, "=:")
+	if index < 0 {
+		return "", "", fmt.Errorf("expect KEY=VALUE")
+	}
+	key := strings.TrimSpace(line[:index])
+	key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+	if !IsValidEnvName(key) {
+		return "", "", fmt.Errorf("invalid variable name %q", key)
+	}
+	return key, strings.TrimLeft(line[index+1:], " \t"), nil
+}
+
+/*
+Parse dotenv content compatible with godotenv. Quoted value can span
+multiple lines, $VAR and ${VAR} are expanded with variables defined in
+previous lines first, then with lookup. Single quoted value is not expanded.
+*/
+func ParseEnvfile(content string, lookup EnvLookup) (map[string]string, error) {
+	env := map[string]string{}
+	envLookup := func(key string) (string, bool) {
+		if v, ok := env[key]; ok {
+			return v, true
+		}
+		return lookup(key)
+	}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineno := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := _splitEnvAssignment(strings.TrimLeft(lines[i], " \t"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineno, err)
+		}
+		if value != "" && (value[0] == '\'' || value[0] == '"') {
+			quote := value[0]
+			end := _findClosingQuote(value, quote)
+			for end < 0 && i+1 < len(lines) {
+				i += 1
+				value += "\n" + lines[i]
+				end = _findClosingQuote(value, quote)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineno)
+			}
+			// 右引号之后的内容视为注释
+			value = value[1:end]
+			if quote == '"' {
+				value = _expandEnvValue(_unescapeEnvValue(value), envLookup)
+			}
+			env[key] = value
+			continue
+		}
+		// 去掉行尾注释
+		for index := 0; index < len(value); index++ {
+			if value[index] == '#' &&
+				(index == 0 || value[index-1] == ' ' || value[index-1] == '\t') {
+				value = value[:index]
+				break
+			}
+		}
+		env[key] = _expandEnvValue(strings.TrimSpace(value), envLookup)
+	}
+	return env, nil
+}
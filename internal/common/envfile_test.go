@@ -0,0 +1,64 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEnvfile(t *testing.T) {
+	lookup := func(key string) (string, bool) {
+		if key == "EZFAAS_BUILD_ID" {
+			return "build-1", true
+		}
+		return "", false
+	}
+	cases := []struct {
+		name    string
+		content string
+		expect  map[string]string
+	}{
+		{"plain", "A=1\nexport B = 2\n", map[string]string{"A": "1", "B": "2"}},
+		{"comment line", "# comment\n\nA=1", map[string]string{"A": "1"}},
+		{"inline comment", "A=1 # comment\nB=x#y", map[string]string{"A": "1", "B": "x#y"}},
+		{"yaml style", "A: 1\nB: http://x", map[string]string{"A": "1", "B": "http://x"}},
+		{"double quoted comment", `A="foo" # comment`, map[string]string{"A": "foo"}},
+		{"single quoted comment", `A='foo # bar' # comment`, map[string]string{"A": "foo # bar"}},
+		{"double quoted escape", `A="a\nb\"c"`, map[string]string{"A": "a\nb\"c"}},
+		{"multiline double quoted", "A=\"line1\nline2\"\nB=2",
+			map[string]string{"A": "line1\nline2", "B": "2"}},
+		{"multiline single quoted", "A='line1\n  $B'\n", map[string]string{"A": "line1\n  $B"}},
+		{"crlf", "A=1\r\nB=\"2\"\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"braces", "A=1\nB=${A}-${EZFAAS_BUILD_ID}", map[string]string{"A": "1", "B": "1-build-1"}},
+		{"no braces", "A=1\nB=$A/x", map[string]string{"A": "1", "B": "1/x"}},
+		{"double quoted expand", "A=1\nB=\"$A ${A}\"", map[string]string{"A": "1", "B": "1 1"}},
+		{"single quoted literal", "A=1\nB='$A ${A}'", map[string]string{"A": "1", "B": "$A ${A}"}},
+		{"escaped dollar", `A=\${B}`, map[string]string{"A": "${B}"}},
+		{"literal dollar brace", "A=${", map[string]string{"A": "${"}},
+		{"undefined", "A=${UNSET}x", map[string]string{"A": "x"}},
+		{"empty", "A=\nB=''", map[string]string{"A": "", "B": ""}},
+	}
+	for _, c := range cases {
+		env, err := ParseEnvfile(c.content, lookup)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(env, c.expect) {
+			t.Errorf("%s: env = %q, expect %q", c.name, env, c.expect)
+		}
+	}
+}
+
+func TestParseEnvfileError(t *testing.T) {
+	lookup := func(key string) (string, bool) { return "", false }
+	for _, content := range []string{
+		"A",
+		"1A=1",
+		"A=\"unterminated\nB=2",
+	} {
+		_, err := ParseEnvfile(content, lookup)
+		if err == nil {
+			t.Errorf("expect error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/* Environment variable rules of a FaaS platform */
+type EnvRules struct {
+	Platform         string
+	NameRegex        *regexp.Regexp
+	NameDescription  string
+	ReservedPrefixes []string
+	MaxTotalSize     int // 所有变量名和值的总字节数
+}
+
+type EnvValidationError struct {
+	Platform   string
+	Violations []string
+}
+
+func (e *EnvValidationError) Error() string {
+	lines := []string{fmt.Sprintf("invalid env variables for %s:", e.Platform)}
+	for _, violation := range e.Violations {
+		lines = append(lines, "  - "+violation)
+	}
+	return strings.Join(lines, "\n")
+}
+
+/* Validate env against platform rules, report all violations at once */
+func ValidateEnv(rules EnvRules, env map[string]string) error {
+	var violations []string
+	totalSize := 0
+	for _, name := range SortedKeys(env) {
+		totalSize += len(name) + len(env[name])
+		if !rules.NameRegex.MatchString(name) {
+			violations = append(violations, fmt.Sprintf(
+				"%s: invalid name, %s", name, rules.NameDescription))
+		}
+		for _, prefix := range rules.ReservedPrefixes {
+			if strings.HasPrefix(strings.ToUpper(name), prefix) {
+				violations = append(violations, fmt.Sprintf(
+					"%s: reserved prefix %s", name, prefix))
+			}
+		}
+	}
+	if rules.MaxTotalSize > 0 && totalSize > rules.MaxTotalSize {
+		violations = append(violations, fmt.Sprintf(
+			"total size %d bytes exceeds limit %d bytes", totalSize, rules.MaxTotalSize))
+	}
+	if len(violations) > 0 {
+		return &EnvValidationError{Platform: rules.Platform, Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateEnv(t *testing.T) {
+	rules := EnvRules{
+		Platform:         "test",
+		NameRegex:        regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`),
+		NameDescription:  "invalid",
+		ReservedPrefixes: []string{"SCF_", "QCLOUD_"},
+		MaxTotalSize:     32,
+	}
+	cases := []struct {
+		name       string
+		env        map[string]string
+		violations []string
+	}{
+		{"valid", map[string]string{"APP_ENV": "prod", "DEBUG": "0"}, nil},
+		{"empty", map[string]string{}, nil},
+		{"invalid name", map[string]string{"1APP": "x", "APP-ENV": "x"},
+			[]string{"1APP: invalid name, invalid", "APP-ENV: invalid name, invalid"}},
+		// 保留前缀不区分大小写
+		{"reserved prefix", map[string]string{"SCF_X": "x", "qcloud_y": "y"},
+			[]string{"SCF_X: reserved prefix SCF_", "qcloud_y: reserved prefix QCLOUD_"}},
+		// 变量值计入总大小
+		{"large value", map[string]string{"A": strings.Repeat("x", 32)},
+			[]string{"total size 33 bytes exceeds limit 32 bytes"}},
+		{"total size", map[string]string{"APP_NAME": "0123456789", "APP_HOST": "0123456789"},
+			[]string{"total size 36 bytes exceeds limit 32 bytes"}},
+		{"all violations", map[string]string{"SCF_1": "x", "1A": strings.Repeat("x", 30)}, []string{
+			"1A: invalid name, invalid",
+			"SCF_1: reserved prefix SCF_",
+			"total size 38 bytes exceeds limit 32 bytes",
+		}},
+	}
+	for _, c := range cases {
+		err := ValidateEnv(rules, c.env)
+		var violations []string
+		if err != nil {
+			validationErr, ok := err.(*EnvValidationError)
+			if !ok {
+				t.Errorf("%s: unexpected error %s", c.name, err)
+				continue
+			}
+			violations = validationErr.Violations
+		}
+		if !reflect.DeepEqual(violations, c.violations) {
+			t.Errorf("%s: violations = %q, expect %q", c.name, violations, c.violations)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+/*
+Env schema declares variables of envfiles, example env.schema.toml:
+
+	strict = true  # unknown variables are not allowed
+
+	[vars.DATABASE_URL]
+	type = "url"
+	required = true
+
+	[vars.LOG_LEVEL]
+	type = "enum"
+	values = ["debug", "info", "warn"]
+	default = "info"
+*/
+const DEFAULT_ENV_SCHEMA_FILE string = "env.schema.toml"
+
+const (
+	ENV_TYPE_STRING string = "string"
+	ENV_TYPE_INT    string = "int"
+	ENV_TYPE_BOOL   string = "bool"
+	ENV_TYPE_URL    string = "url"
+	ENV_TYPE_ENUM   string = "enum"
+)
+
+type EnvVarSchema struct {
+	Type        string   `toml:"type"`
+	Required    bool     `toml:"required"`
+	Default     *string  `toml:"default"`
+	Values      []string `toml:"values"`
+	Description string   `toml:"description"`
+}
+
+type EnvSchema struct {
+	Strict bool                    `toml:"strict"`
+	Vars   map[string]EnvVarSchema `toml:"vars"`
+}
+
+func LoadEnvSchema(filepath string) (*EnvSchema, error) {
+	data, err := ReadUserFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	var schema EnvSchema
+	err = toml.Unmarshal(data, &schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath, err)
+	}
+	for name, varSchema := range schema.Vars {
+		if varSchema.Type == "" {
+			varSchema.Type = ENV_TYPE_STRING
+			schema.Vars[name] = varSchema
+		}
+		if err := _checkEnvVarSchema(varSchema); err != nil {
+			return nil, fmt.Errorf("%s: %s: %s", filepath, name, err)
+		}
+	}
+	return &schema, nil
+}
+
+func _checkEnvVarSchema(varSchema EnvVarSchema) error {
+	switch varSchema.Type {
+	case ENV_TYPE_STRING, ENV_TYPE_INT, ENV_TYPE_BOOL, ENV_TYPE_URL:
+	case ENV_TYPE_ENUM:
+		if len(varSchema.Values) <= 0 {
+			return fmt.Errorf("enum values is required")
+		}
+	default:
+		return fmt.Errorf("invalid type %q", varSchema.Type)
+	}
+	if varSchema.Default != nil {
+		return _checkEnvValueType(varSchema, *varSchema.Default)
+	}
+	return nil
+}
+
+func _checkEnvValueType(varSchema EnvVarSchema, value string) error {
+	switch varSchema.Type {
+	case ENV_TYPE_INT:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("expect int")
+		}
+	case ENV_TYPE_BOOL:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expect bool")
+		}
+	case ENV_TYPE_URL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("expect url")
+		}
+	case ENV_TYPE_ENUM:
+		for _, v := range varSchema.Values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("expect one of %s", strings.Join(varSchema.Values, ", "))
+	}
+	return nil
+}
+
+/* Get default values of variables */
+func GetEnvSchemaDefaults(schema *EnvSchema) map[string]string {
+	defaults := map[string]string{}
+	if schema == nil {
+		return defaults
+	}
+	for name, varSchema := range schema.Vars {
+		if varSchema.Default != nil {
+			defaults[name] = *varSchema.Default
+		}
+	}
+	return defaults
+}
+
+/*
+Check env against schema, report all violations at once. Missing required
+variables are only reported if checkRequired is true.
+*/
+func CheckEnvSchema(schema *EnvSchema, env map[string]string, checkRequired bool) error {
+	if schema == nil {
+		return nil
+	}
+	var violations []string
+	var names []string
+	for name := range schema.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		varSchema := schema.Vars[name]
+		value, ok := env[name]
+		if !ok {
+			if checkRequired && varSchema.Required && varSchema.Default == nil {
+				violations = append(violations, fmt.Sprintf("%s: required", name))
+			}
+			continue
+		}
+		if err := _checkEnvValueType(varSchema, value); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+	if schema.Strict {
+		for _, name := range SortedKeys(env) {
+			if _, ok := schema.Vars[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: not declared in schema", name))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &EnvValidationError{Platform: "env schema", Violations: violations}
+	}
+	return nil
+}
+
+type EnvKeyDiff struct {
+	Added   []string // 只在新envfile中的变量
+	Removed []string // 只在旧envfile中的变量
+	Changed []string // 值不同的变量
+}
+
+/* Diff variable names of two envs, values are not included */
+func DiffEnvKeys(oldEnv map[string]string, newEnv map[string]string) EnvKeyDiff {
+	var diff EnvKeyDiff
+	for _, name := range SortedKeys(oldEnv) {
+		newValue, ok := newEnv[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, name)
+		} else if newValue != oldEnv[name] {
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for _, name := range SortedKeys(newEnv) {
+		if _, ok := oldEnv[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	return diff
+}
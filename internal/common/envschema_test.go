@@ -0,0 +1,165 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvSchema(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		content string
+		err     string // 错误信息包含的内容，为空表示成功
+	}{
+		{"default type", "[vars.NAME]\nrequired = true", ""},
+		{"enum", "[vars.LEVEL]\ntype = \"enum\"\nvalues = [\"debug\", \"info\"]\ndefault = \"info\"", ""},
+		{"invalid type", "[vars.NAME]\ntype = \"float\"", "invalid type"},
+		{"enum without values", "[vars.LEVEL]\ntype = \"enum\"", "enum values is required"},
+		{"invalid default", "[vars.PORT]\ntype = \"int\"\ndefault = \"x\"", "PORT: expect int"},
+		{"invalid toml", "[vars.NAME", "schema"},
+	}
+	for i, c := range cases {
+		schemaFile := filepath.Join(dir, "schema"+string(rune('a'+i))+".toml")
+		if err := os.WriteFile(schemaFile, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		schema, err := LoadEnvSchema(schemaFile)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: error = %v, expect %q", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		for name, varSchema := range schema.Vars {
+			if varSchema.Type == "" {
+				t.Errorf("%s: type of %s is empty", c.name, name)
+			}
+		}
+	}
+}
+
+func TestCheckEnvSchema(t *testing.T) {
+	level := "info"
+	schema := &EnvSchema{Vars: map[string]EnvVarSchema{
+		"DATABASE_URL": {Type: ENV_TYPE_URL, Required: true},
+		"PORT":         {Type: ENV_TYPE_INT},
+		"DEBUG":        {Type: ENV_TYPE_BOOL},
+		"LEVEL":        {Type: ENV_TYPE_ENUM, Values: []string{"debug", "info"}, Required: true, Default: &level},
+	}}
+	strictSchema := &EnvSchema{Strict: true, Vars: schema.Vars}
+	cases := []struct {
+		name          string
+		schema        *EnvSchema
+		env           map[string]string
+		checkRequired bool
+		violations    []string
+	}{
+		{"valid", schema, map[string]string{
+			"DATABASE_URL": "postgres://db:5432/app", "PORT": "80", "DEBUG": "true", "LEVEL": "debug",
+		}, true, nil},
+		{"nil schema", nil, map[string]string{"X": "1"}, true, nil},
+		// 有默认值的必填变量不报错
+		{"required", schema, map[string]string{}, true, []string{"DATABASE_URL: required"}},
+		{"required not checked", schema, map[string]string{}, false, nil},
+		{"types", schema, map[string]string{
+			"DATABASE_URL": "db", "PORT": "x", "DEBUG": "yes", "LEVEL": "warn",
+		}, false, []string{
+			"DATABASE_URL: expect url",
+			"DEBUG: expect bool",
+			"LEVEL: expect one of debug, info",
+			"PORT: expect int",
+		}},
+		{"strict", strictSchema, map[string]string{"PORT": "80", "OTHER": "x"}, false,
+			[]string{"OTHER: not declared in schema"}},
+		{"not strict", schema, map[string]string{"PORT": "80", "OTHER": "x"}, false, nil},
+	}
+	for _, c := range cases {
+		err := CheckEnvSchema(c.schema, c.env, c.checkRequired)
+		var violations []string
+		if err != nil {
+			violations = err.(*EnvValidationError).Violations
+		}
+		if !reflect.DeepEqual(violations, c.violations) {
+			t.Errorf("%s: violations = %q, expect %q", c.name, violations, c.violations)
+		}
+	}
+}
+
+func TestGetEnvSchemaDefaults(t *testing.T) {
+	level := "info"
+	schema := &EnvSchema{Vars: map[string]EnvVarSchema{
+		"LEVEL": {Type: ENV_TYPE_STRING, Default: &level},
+		"PORT":  {Type: ENV_TYPE_INT},
+	}}
+	defaults := GetEnvSchemaDefaults(schema)
+	if !reflect.DeepEqual(defaults, map[string]string{"LEVEL": "info"}) {
+		t.Errorf("defaults = %v", defaults)
+	}
+	if len(GetEnvSchemaDefaults(nil)) != 0 {
+		t.Error("defaults of nil schema should be empty")
+	}
+}
+
+func TestDiffEnvKeys(t *testing.T) {
+	cases := []struct {
+		name   string
+		oldEnv map[string]string
+		newEnv map[string]string
+		expect EnvKeyDiff
+	}{
+		{"same", map[string]string{"A": "1"}, map[string]string{"A": "1"}, EnvKeyDiff{}},
+		{"changes",
+			map[string]string{"A": "1", "B": "2", "C": "3"},
+			map[string]string{"B": "2", "C": "x", "D": "4", "E": "5"},
+			EnvKeyDiff{Added: []string{"D", "E"}, Removed: []string{"A"}, Changed: []string{"C"}}},
+		{"empty old", map[string]string{}, map[string]string{"A": "1"}, EnvKeyDiff{Added: []string{"A"}}},
+	}
+	for _, c := range cases {
+		diff := DiffEnvKeys(c.oldEnv, c.newEnv)
+		if !reflect.DeepEqual(diff, c.expect) {
+			t.Errorf("%s: diff = %+v, expect %+v", c.name, diff, c.expect)
+		}
+	}
+}
+
+func TestResolveEnvSchema(t *testing.T) {
+	level := "info"
+	schema := &EnvSchema{Strict: true, Vars: map[string]EnvVarSchema{
+		"A":     {Type: ENV_TYPE_INT, Required: true},
+		"LEVEL": {Type: ENV_TYPE_STRING, Default: &level},
+	}}
+	current := map[string]string{"A": "1"}
+	cases := []struct {
+		name   string
+		update EnvUpdate
+		expect map[string]string // nil表示检查失败
+	}{
+		{"default", EnvUpdate{Mode: ENV_MODE_PATCH, Schema: schema},
+			map[string]string{"A": "1", "LEVEL": "info"}},
+		{"invalid type", EnvUpdate{Mode: ENV_MODE_PATCH, Schema: schema,
+			SetEnv: map[string]string{"A": "x"}}, nil},
+		{"required", EnvUpdate{Mode: ENV_MODE_PATCH, Schema: schema, UnsetList: []string{"A"}}, nil},
+		{"strict", EnvUpdate{Mode: ENV_MODE_PATCH, Schema: schema,
+			SetEnv: map[string]string{"B": "2"}}, nil},
+	}
+	for _, c := range cases {
+		env, _, err := ResolveEnv(c.update, current)
+		if c.expect == nil {
+			if err == nil {
+				t.Errorf("%s: expect error", c.name)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(env, c.expect) {
+			t.Errorf("%s: env = %v err = %v, expect %v", c.name, env, err, c.expect)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	gofilepath "path/filepath"
+	"reflect"
 
 	"github.com/manifoldco/promptui"
 	"github.com/mitchellh/go-homedir"
@@ -19,6 +20,14 @@ func ReadUserFile(filepath string) ([]byte, error) {
 	return os.ReadFile(gofilepath.ToSlash(filepath))
 }
 
+func WriteUserFile(filepath string, data []byte, perm os.FileMode) error {
+	filepath, err := homedir.Expand(filepath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(gofilepath.ToSlash(filepath), data, perm)
+}
+
 var ErrCanceled = fmt.Errorf("canceled")
 
 func Comfirm(label string) bool {
@@ -38,3 +47,38 @@ func LogPrettyJSON(output interface{}) {
 	outputBytes, _ := json.MarshalIndent(output, "", "    ")
 	log.Printf("%s\n", string(outputBytes))
 }
+
+func _isJSONSubset(expected interface{}, actual interface{}) bool {
+	expectedMap, ok := expected.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(expected, actual)
+	}
+	actualMap, ok := actual.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for key, value := range expectedMap {
+		if !_isJSONSubset(value, actualMap[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Check every field of expected JSON exists in actual JSON with the same value,
+platforms usually return normalized config with extra fields.
+*/
+func IsJSONSubset(expected string, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	var expectedValue, actualValue interface{}
+	if json.Unmarshal([]byte(expected), &expectedValue) != nil {
+		return false
+	}
+	if json.Unmarshal([]byte(actual), &actualValue) != nil {
+		return false
+	}
+	return _isJSONSubset(expectedValue, actualValue)
+}
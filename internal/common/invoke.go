@@ -0,0 +1,82 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	INVOKE_MODE_SYNC  string = "sync"
+	INVOKE_MODE_ASYNC string = "async"
+)
+
+const (
+	PAYLOAD_TEMPLATE_TIMER string = "timer"
+	PAYLOAD_TEMPLATE_APIGW string = "apigw"
+	PAYLOAD_TEMPLATE_COS   string = "cos"
+)
+
+/* Built-in event payload templates of a platform, keyed by template name */
+type PayloadTemplates map[string]string
+
+/* Load payload from file, or template if file is not specified */
+func LoadInvokePayload(payloadFile string, template string, templates PayloadTemplates) (string, error) {
+	if payloadFile != "" && template != "" {
+		return "", fmt.Errorf("payload file and template can not be used together")
+	}
+	if payloadFile != "" {
+		data, err := ReadUserFile(payloadFile)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	if template == "" {
+		return "{}", nil
+	}
+	payload, ok := templates[template]
+	if !ok {
+		var names []string
+		for name := range templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("invalid payload template %q, expect one of %s",
+			template, strings.Join(names, ", "))
+	}
+	return payload, nil
+}
+
+type InvokeResult struct {
+	RequestId      string
+	Body           string
+	Error          string  // 函数执行错误，为空表示成功
+	Duration       float64 // 毫秒
+	BilledDuration int64   // 毫秒，0表示平台未返回
+	MemorySize     int64   // 计费内存MB，即函数配置的内存
+	MemUsage       float64 // 实际使用内存MB
+	Log            string  // 日志末尾部分
+}
+
+/* Print log tail and stats to stderr, and response body to stdout */
+func PrintInvokeResult(result *InvokeResult) {
+	if result.Log != "" {
+		fmt.Fprintln(os.Stderr, strings.TrimRight(result.Log, "\n"))
+	}
+	billedDuration := ""
+	if result.BilledDuration > 0 {
+		billedDuration = fmt.Sprintf(" BilledDuration=%dms", result.BilledDuration)
+	}
+	log.Printf("[INFO] RequestId=%s Duration=%.2fms%s Memory=%dMB MemUsage=%.2fMB",
+		result.RequestId, result.Duration, billedDuration,
+		result.MemorySize, result.MemUsage)
+	if result.Error != "" {
+		log.Printf("[WARN] Function error: %s", result.Error)
+	}
+	if result.Body != "" {
+		fmt.Println(result.Body)
+	}
+}
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/* Convert http request to event of api gateway or http trigger */
+type HTTPEventEncoder func(request *http.Request, body []byte, requestId string) map[string]interface{}
+
+/* Response of event function for api gateway or http trigger */
+type HTTPEventResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+/* Body is base64 encoded if it is not valid utf8 text */
+func EncodeEventBody(body []byte) (string, bool) {
+	if utf8.Valid(body) {
+		return string(body), false
+	}
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+func JoinHeaderValues(header http.Header, isLower bool) map[string]string {
+	result := map[string]string{}
+	for k, values := range header {
+		if isLower {
+			k = strings.ToLower(k)
+		}
+		result[k] = strings.Join(values, ",")
+	}
+	return result
+}
+
+func FirstQueryValues(query url.Values) map[string]string {
+	result := map[string]string{}
+	for k, values := range query {
+		if len(values) > 0 {
+			result[k] = values[0]
+		}
+	}
+	return result
+}
+
+func GetSourceIp(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+/*
+Decode event response, the raw body is returned with status 200 if it is not
+a structured response.
+*/
+func _decodeHTTPEventResponse(data []byte) (*HTTPEventResponse, []byte, error) {
+	var response HTTPEventResponse
+	err := json.Unmarshal(data, &response)
+	if err != nil || response.StatusCode <= 0 {
+		return &HTTPEventResponse{StatusCode: http.StatusOK}, data, nil
+	}
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		body, err = base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return &response, body, nil
+}
+
+/*
+Serve local api gateway for event function, each http request is converted to
+event of the platform and the function response is mapped back.
+*/
+func ServeLocalGateway(
+	listen string,
+	containerUrl string,
+	runtime LocalRuntime,
+	timeout time.Duration,
+) error {
+	client := http.Client{Timeout: timeout}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requestId := _newRequestId()
+		event := runtime.HTTPEventEncoder(r, body, requestId)
+		payload, err := json.Marshal(event)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response, err := _invokeLocalEvent(&client, containerUrl, runtime, payload, requestId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer response.Body.Close()
+		data, err := io.ReadAll(response.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if response.StatusCode != http.StatusOK {
+			log.Printf("[WARN] %s %s RequestId=%s function error: %s",
+				r.Method, r.URL.Path, requestId, string(data))
+			http.Error(w, string(data), http.StatusBadGateway)
+			return
+		}
+		eventResponse, responseBody, err := _decodeHTTPEventResponse(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		for k, v := range eventResponse.Headers {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set(runtime.RequestIdHeader, requestId)
+		w.WriteHeader(eventResponse.StatusCode)
+		_, _ = w.Write(responseBody)
+		log.Printf("[INFO] %s %s %d", r.Method, r.URL.RequestURI(), eventResponse.StatusCode)
+	}
+	log.Printf("[INFO] Local api gateway http://%s -> %s%s",
+		listen, containerUrl, runtime.EventPath)
+	return http.ListenAndServe(listen, http.HandlerFunc(handler))
+}
@@ -0,0 +1,103 @@
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+/* Function config to emulate platform runtime locally */
+type LocalRunConfig struct {
+	FunctionName string
+	Region       string
+	MemorySize   int // MB
+	Timeout      int // 秒
+	Port         int // 容器监听端口，0表示平台默认端口
+}
+
+/* Platform runtime conventions of custom container function */
+type LocalRuntime struct {
+	Platform         string
+	Env              map[string]string // 平台注入的运行时环境变量
+	ContainerPort    int
+	EventPath        string            // 事件函数的调用路径
+	EventHeaders     map[string]string // 事件调用时平台添加的请求头
+	RequestIdHeader  string
+	HTTPEventEncoder HTTPEventEncoder // 将HTTP请求转换为网关/HTTP触发器事件
+}
+
+/* Random UUID v4 as request id */
+func _newRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func _invokeLocalEvent(
+	client *http.Client,
+	containerUrl string,
+	runtime LocalRuntime,
+	payload []byte,
+	requestId string,
+) (*http.Response, error) {
+	request, err := http.NewRequest(
+		http.MethodPost, containerUrl+runtime.EventPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range runtime.EventHeaders {
+		request.Header.Set(k, v)
+	}
+	request.Header.Set(runtime.RequestIdHeader, requestId)
+	begin := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		log.Printf("[WARN] Invoke RequestId=%s %s", requestId, err)
+		return nil, err
+	}
+	log.Printf("[INFO] Invoke RequestId=%s Status=%d Duration=%s",
+		requestId, response.StatusCode, time.Since(begin).Round(time.Millisecond))
+	return response, nil
+}
+
+/*
+Serve local invoke endpoint for event function, the payload is forwarded to
+the container the same way the platform does.
+*/
+func ServeLocalInvoke(
+	listen string,
+	containerUrl string,
+	runtime LocalRuntime,
+	timeout time.Duration,
+) error {
+	client := http.Client{Timeout: timeout}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requestId := _newRequestId()
+		response, err := _invokeLocalEvent(&client, containerUrl, runtime, payload, requestId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer response.Body.Close()
+		w.Header().Set("Content-Type", response.Header.Get("Content-Type"))
+		w.Header().Set(runtime.RequestIdHeader, requestId)
+		w.WriteHeader(response.StatusCode)
+		_, _ = io.Copy(w, response.Body)
+	}
+	log.Printf("[INFO] Local invoke endpoint http://%s", listen)
+	return http.ListenAndServe(listen, http.HandlerFunc(handler))
+}
@@ -0,0 +1,219 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+)
+
+/* Log entry of function, unified for all platforms */
+type FunctionLog struct {
+	Time      time.Time
+	RequestId string
+	Message   string
+	IsError   bool
+	// 日志是请求到目前为止的全部输出，每次查询可能继续增长，例如腾讯云
+	IsRequestOutput bool
+	IsRunning       bool // 请求未结束，最后一行可能不完整
+}
+
+func (l FunctionLog) _key() string {
+	return fmt.Sprintf("%d/%s/%s", l.Time.UnixNano(), l.RequestId, l.Message)
+}
+
+/* Fetch function logs in time range [start, end) */
+type LogFetcher func(start time.Time, end time.Time) ([]FunctionLog, error)
+
+type TailLogsParams struct {
+	Since    time.Time
+	Follow   bool
+	Filter   string // 日志内容正则，为空表示不过滤
+	Interval time.Duration
+}
+
+var (
+	_styleTime      = promptui.Styler(promptui.FGFaint)
+	_styleRequestId = promptui.Styler(promptui.FGCyan)
+	_styleError     = promptui.Styler(promptui.FGRed)
+)
+
+var _errorLogPattern = regexp.MustCompile(`\b(ERROR|Error|FATAL|Fatal|Traceback|panic)\b`)
+
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+/* Print log with time and request id prefix, each line of message is prefixed */
+func PrintFunctionLog(entry FunctionLog, isColor bool) {
+	timeText := entry.Time.Local().Format("2006-01-02 15:04:05.000")
+	requestId := entry.RequestId
+	if requestId == "" {
+		requestId = "-"
+	}
+	if isColor {
+		timeText = _styleTime(timeText)
+		requestId = _styleRequestId(requestId)
+	}
+	for _, line := range strings.Split(strings.TrimRight(entry.Message, "\n"), "\n") {
+		if isColor && (entry.IsError || _errorLogPattern.MatchString(line)) {
+			line = _styleError(line)
+		}
+		fmt.Printf("%s %s %s\n", timeText, requestId, line)
+	}
+}
+
+/*
+Get new complete lines of growing request output, the last line may be
+incomplete until the request finished.
+*/
+func _getNewRequestOutput(message string, printed int, isFinished bool) string {
+	if len(message) <= printed {
+		return ""
+	}
+	message = message[printed:]
+	if !isFinished {
+		message = message[:strings.LastIndex(message, "\n")+1]
+	}
+	return message
+}
+
+/*
+Print logs in time order, duplicated and filtered logs are skipped. Request
+output logs are deduped by request id, only new lines are printed.
+*/
+type LogTail struct {
+	filter  *regexp.Regexp
+	isColor bool
+	seen    map[string]time.Time
+	printed map[string]int // requestId -> 已输出的日志长度
+}
+
+func NewLogTail(filter string) (*LogTail, error) {
+	tail := LogTail{
+		isColor: IsTerminal(os.Stdout),
+		seen:    map[string]time.Time{},
+		printed: map[string]int{},
+	}
+	if filter != "" {
+		var err error
+		tail.filter, err = regexp.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %s", err)
+		}
+	}
+	return &tail, nil
+}
+
+func (t *LogTail) Print(logs []FunctionLog) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Time.Before(logs[j].Time)
+	})
+	for _, entry := range logs {
+		if entry.IsRequestOutput {
+			key := "request/" + entry.RequestId
+			printed := t.printed[key]
+			message := _getNewRequestOutput(entry.Message, printed, !entry.IsRunning)
+			if message == "" {
+				continue
+			}
+			t.seen[key] = entry.Time
+			t.printed[key] = printed + len(message)
+			entry.Message = message
+		} else {
+			key := entry._key()
+			if _, ok := t.seen[key]; ok {
+				continue
+			}
+			t.seen[key] = entry.Time
+		}
+		if t.filter != nil && !t.filter.MatchString(entry.Message) {
+			continue
+		}
+		PrintFunctionLog(entry, t.isColor)
+	}
+}
+
+/* Forget logs before the time, they will not be fetched again */
+func (t *LogTail) Forget(before time.Time) {
+	for key, entryTime := range t.seen {
+		if entryTime.Before(before) {
+			delete(t.seen, key)
+			delete(t.printed, key)
+		}
+	}
+}
+
+/*
+Print growing logs of requests, each log is the whole output of a request
+so far. Only new complete lines are printed until the request finished.
+*/
+type RequestLogTail struct {
+	isColor bool
+	printed map[string]int // requestId -> 已输出的日志长度
+}
+
+func NewRequestLogTail() *RequestLogTail {
+	return &RequestLogTail{
+		isColor: IsTerminal(os.Stdout),
+		printed: map[string]int{},
+	}
+}
+
+func (t *RequestLogTail) Print(logs []FunctionLog, isFinished bool) {
+	for _, entry := range logs {
+		printed := t.printed[entry.RequestId]
+		message := _getNewRequestOutput(entry.Message, printed, isFinished)
+		if message == "" {
+			continue
+		}
+		t.printed[entry.RequestId] = printed + len(message)
+		entry.Message = message
+		PrintFunctionLog(entry, t.isColor)
+	}
+}
+
+/*
+Print logs since the time, and keep polling new logs if follow. Logs may
+arrive late, so each poll overlaps the previous one and duplicates are skipped.
+*/
+func TailLogs(params TailLogsParams, fetch LogFetcher) error {
+	tail, err := NewLogTail(params.Filter)
+	if err != nil {
+		return err
+	}
+	interval := params.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	start := params.Since
+	for {
+		end := time.Now()
+		logs, err := fetch(start, end)
+		if err != nil {
+			if !params.Follow {
+				return err
+			}
+			log.Printf("[WARN] Fetch logs: %s", err)
+		}
+		tail.Print(logs)
+		if !params.Follow {
+			return nil
+		}
+		// 日志投递有延迟，保留一分钟重叠窗口
+		if overlap := end.Add(-time.Minute); overlap.After(start) {
+			start = overlap
+		}
+		tail.Forget(start)
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,153 @@
+package common
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* Capture stdout of function */
+func _captureStdout(t *testing.T, f func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+	f()
+	writer.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLogTailDedupe(t *testing.T) {
+	now := time.Now()
+	first := FunctionLog{Time: now, RequestId: "r1", Message: "start"}
+	second := FunctionLog{Time: now.Add(time.Second), RequestId: "r1", Message: "done"}
+	other := FunctionLog{Time: now.Add(time.Second), RequestId: "r2", Message: "done"}
+	cases := []struct {
+		name   string
+		filter string
+		polls  [][]FunctionLog
+		expect []string // 按顺序输出的"requestId message"，不含时间
+	}{
+		{
+			name:   "overlapped polls",
+			polls:  [][]FunctionLog{{first}, {first, second}, {second, other}},
+			expect: []string{"r1 start", "r1 done", "r2 done"},
+		},
+		{
+			name:   "sorted by time",
+			polls:  [][]FunctionLog{{second, first}},
+			expect: []string{"r1 start", "r1 done"},
+		},
+		{
+			name:   "filter",
+			filter: "^done$",
+			polls:  [][]FunctionLog{{first, second}, {first, second}},
+			expect: []string{"r1 done"},
+		},
+	}
+	for _, c := range cases {
+		tail, err := NewLogTail(c.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tail.isColor = false
+		output := _captureStdout(t, func() {
+			for _, logs := range c.polls {
+				tail.Print(append([]FunctionLog{}, logs...))
+			}
+		})
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			// 时间格式包含一个空格
+			parts := strings.SplitN(line, " ", 3)
+			lines = append(lines, parts[2])
+		}
+		if strings.Join(lines, "\n") != strings.Join(c.expect, "\n") {
+			t.Errorf("%s: output = %q, expect %q", c.name, lines, c.expect)
+		}
+	}
+}
+
+func TestLogTailRequestOutput(t *testing.T) {
+	now := time.Now()
+	polls := []FunctionLog{
+		{Message: "line1\nli", IsRunning: true},
+		{Message: "line1\nline2\n", IsRunning: true},
+		{Message: "line1\nline2\nend"},
+		{Message: "line1\nline2\nend"},
+	}
+	tail, _ := NewLogTail("")
+	tail.isColor = false
+	output := _captureStdout(t, func() {
+		for _, entry := range polls {
+			entry.Time = now
+			entry.RequestId = "r1"
+			entry.IsRequestOutput = true
+			tail.Print([]FunctionLog{entry})
+		}
+	})
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		lines = append(lines, strings.SplitN(line, " ", 4)[3])
+	}
+	expect := []string{"line1", "line2", "end"}
+	if strings.Join(lines, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("output = %q, expect %q", lines, expect)
+	}
+	tail.Forget(now.Add(time.Second))
+	if len(tail.seen) != 0 || len(tail.printed) != 0 {
+		t.Errorf("seen = %v printed = %v, expect empty", tail.seen, tail.printed)
+	}
+}
+
+func TestLogTailForget(t *testing.T) {
+	now := time.Now()
+	tail, _ := NewLogTail("")
+	tail.isColor = false
+	entry := FunctionLog{Time: now, RequestId: "r1", Message: "start"}
+	_captureStdout(t, func() { tail.Print([]FunctionLog{entry}) })
+	tail.Forget(now.Add(time.Second))
+	if len(tail.seen) != 0 {
+		t.Errorf("seen = %v, expect empty", tail.seen)
+	}
+}
+
+func TestRequestLogTail(t *testing.T) {
+	now := time.Now()
+	tail := NewRequestLogTail()
+	tail.isColor = false
+	polls := []struct {
+		message    string
+		isFinished bool
+	}{
+		{"", false},
+		{"line1\nli", false},
+		{"line1\nline2\nline3", false},
+		{"line1\nline2\nline3", false},
+		{"line1\nline2\nline3\nend", true},
+		{"line1\nline2\nline3\nend", true},
+	}
+	output := _captureStdout(t, func() {
+		for _, poll := range polls {
+			entry := FunctionLog{Time: now, RequestId: "r1", Message: poll.message}
+			tail.Print([]FunctionLog{entry}, poll.isFinished)
+		}
+	})
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		lines = append(lines, strings.SplitN(line, " ", 4)[3])
+	}
+	expect := []string{"line1", "line2", "line3", "end"}
+	if strings.Join(lines, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("output = %q, expect %q", lines, expect)
+	}
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+const SECRET_MASK string = "******"
+
+/*
+Secret reference in envfile value:
+
+	DB_PASS=ssm://prod/db#password
+	DB_PASS=kms://prod-db?region=cn-hangzhou#password
+	DB_PASS=secretfile://./secrets.json#db_pass
+
+The part after # is a key of the JSON secret, the whole secret is used if
+no key specified. Local file uses secretfile scheme, so plain file:// URLs
+are not treated as secret references.
+*/
+type SecretRef struct {
+	Scheme string
+	Name   string
+	Key    string
+	Query  url.Values
+}
+
+func (ref SecretRef) String() string {
+	text := ref.Scheme + "://" + ref.Name
+	if len(ref.Query) > 0 {
+		text += "?" + ref.Query.Encode()
+	}
+	if ref.Key != "" {
+		text += "#" + ref.Key
+	}
+	return text
+}
+
+type SecretResolver interface {
+	ResolveSecret(ref SecretRef) (string, error)
+}
+
+/* Parse secret reference, ok is false if value is not a reference of the schemes */
+func ParseSecretRef(value string, schemes []string) (*SecretRef, bool, error) {
+	var scheme string
+	for _, s := range schemes {
+		if strings.HasPrefix(value, s+"://") {
+			scheme = s
+			break
+		}
+	}
+	if scheme == "" {
+		return nil, false, nil
+	}
+	ref := SecretRef{Scheme: scheme, Query: url.Values{}}
+	rest := strings.TrimPrefix(value, scheme+"://")
+	if index := strings.Index(rest, "#"); index >= 0 {
+		rest, ref.Key = rest[:index], rest[index+1:]
+	}
+	if index := strings.Index(rest, "?"); index >= 0 {
+		query, err := url.ParseQuery(rest[index+1:])
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid secret reference %s: %s", value, err)
+		}
+		rest, ref.Query = rest[:index], query
+	}
+	if rest == "" {
+		return nil, true, fmt.Errorf("invalid secret reference %s: empty name", value)
+	}
+	ref.Name = rest
+	return &ref, true, nil
+}
+
+/* Get value of key in JSON object secret, return the secret if key is empty */
+func GetSecretKeyValue(ref SecretRef, secret string) (string, error) {
+	if ref.Key == "" {
+		return secret, nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &data); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object", ref.Name)
+	}
+	value, ok := data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(valueBytes), nil
+}
+
+/*
+Resolve secret references in env, return the resolved env and names of
+resolved variables. Resolved values are never logged.
+*/
+func ResolveSecretEnv(
+	env map[string]string,
+	resolvers map[string]SecretResolver,
+) (map[string]string, []string, error) {
+	var schemes []string
+	for scheme := range resolvers {
+		schemes = append(schemes, scheme)
+	}
+	result := map[string]string{}
+	var secretNames []string
+	for _, name := range SortedKeys(env) {
+		value := env[name]
+		ref, ok, err := ParseSecretRef(value, schemes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			result[name] = value
+			continue
+		}
+		log.Printf("[INFO] Resolve secret %s=%s", name, ref.String())
+		secret, err := resolvers[ref.Scheme].ResolveSecret(*ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolve secret %s failed: %s", name, err)
+		}
+		result[name] = secret
+		secretNames = append(secretNames, name)
+	}
+	return result, secretNames, nil
+}
+
+/* Resolve secretfile://path#key reference from local JSON or plain text file, for testing */
+type FileSecretResolver struct{}
+
+func (r FileSecretResolver) ResolveSecret(ref SecretRef) (string, error) {
+	data, err := ReadUserFile(ref.Name)
+	if err != nil {
+		return "", err
+	}
+	return GetSecretKeyValue(ref, strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	schemes := []string{"ssm", "kms", "secretfile"}
+	cases := []struct {
+		value  string
+		ok     bool
+		isErr  bool
+		expect string
+	}{
+		{"ssm://prod/db#password", true, false, "ssm://prod/db#password"},
+		{"kms://prod-db?region=cn-hangzhou#password", true, false, "kms://prod-db?region=cn-hangzhou#password"},
+		{"secretfile://./secrets.json", true, false, "secretfile://./secrets.json"},
+		{"file:///data", false, false, ""},
+		{"https://example.com/ssm://x", false, false, ""},
+		{"plain value", false, false, ""},
+		{"ssm://#password", true, true, ""},
+		{"kms://name?a=%zz", true, true, ""},
+	}
+	for _, c := range cases {
+		ref, ok, err := ParseSecretRef(c.value, schemes)
+		if ok != c.ok || (err != nil) != c.isErr {
+			t.Errorf("ParseSecretRef(%q) ok=%v err=%v", c.value, ok, err)
+			continue
+		}
+		if ref != nil && ref.String() != c.expect {
+			t.Errorf("ParseSecretRef(%q) = %s, expect %s", c.value, ref.String(), c.expect)
+		}
+	}
+}
+
+func TestGetSecretKeyValue(t *testing.T) {
+	secret := `{"password": "p@ss", "port": 5432}`
+	cases := []struct {
+		key    string
+		expect string
+	}{
+		{"", secret},
+		{"password", "p@ss"},
+		{"port", "5432"},
+	}
+	for _, c := range cases {
+		value, err := GetSecretKeyValue(SecretRef{Name: "db", Key: c.key}, secret)
+		if err != nil || value != c.expect {
+			t.Errorf("GetSecretKeyValue(%q) = %q, %v", c.key, value, err)
+		}
+	}
+	if _, err := GetSecretKeyValue(SecretRef{Name: "db", Key: "missing"}, secret); err == nil {
+		t.Error("expect error for missing key")
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -153,3 +154,98 @@ func DockerPush(p DockerPushParams) error {
 	commandArgs = append(commandArgs, "push", p.Image)
 	return Shell("docker", commandArgs...)
 }
+
+type DockerRunParams struct {
+	Image    string
+	Name     string
+	Platform string
+	Env      map[string]string
+	Ports    []string // 端口映射，例如: 9000:9000
+	MemoryMB int      // 内存限制，0表示不限制
+}
+
+/* Call docker run command, env values are passed by environment to hide them from process list */
+func DockerRun(p DockerRunParams) error {
+	commandArgs := []string{"run", "--rm", "--name", p.Name}
+	if p.Platform != "" {
+		commandArgs = append(commandArgs, "--platform", p.Platform)
+	}
+	if p.MemoryMB > 0 {
+		commandArgs = append(commandArgs, "--memory", fmt.Sprintf("%dm", p.MemoryMB))
+	}
+	for _, port := range p.Ports {
+		commandArgs = append(commandArgs, "-p", port)
+	}
+	envList := os.Environ()
+	for _, k := range SortedKeys(p.Env) {
+		commandArgs = append(commandArgs, "-e", k)
+		envList = append(envList, fmt.Sprintf("%s=%s", k, p.Env[k]))
+	}
+	commandArgs = append(commandArgs, p.Image)
+	cmd := exec.Command("docker", commandArgs...)
+	cmd.Env = envList
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+/*
+Split command line to arguments, JSON array is accepted, otherwise split
+in shell style with single quotes, double quotes and backslash escapes.
+*/
+func SplitCommandLine(text string) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(text), &args); err != nil {
+			return nil, fmt.Errorf("invalid command JSON array %s: %s", text, err)
+		}
+		return args, nil
+	}
+	args := []string{}
+	var builder strings.Builder
+	isArg := false
+	var quote byte = 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				builder.WriteByte(c)
+			}
+		case c == '\\' && i+1 < len(text) &&
+			(quote == 0 || strings.IndexByte(`"\$`+"`", text[i+1]) >= 0):
+			builder.WriteByte(text[i+1])
+			isArg = true
+			i += 1
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				builder.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			isArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if isArg {
+				args = append(args, builder.String())
+				builder.Reset()
+				isArg = false
+			}
+		default:
+			builder.WriteByte(c)
+			isArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in command %s", text)
+	}
+	if isArg {
+		args = append(args, builder.String())
+	}
+	return args, nil
+}
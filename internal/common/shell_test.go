@@ -0,0 +1,39 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	cases := []struct {
+		text   string
+		expect []string
+	}{
+		{"", []string{}},
+		{"python app.py", []string{"python", "app.py"}},
+		{"  a   b\tc ", []string{"a", "b", "c"}},
+		{`echo "hello world"`, []string{"echo", "hello world"}},
+		{`echo 'a "b" $c'`, []string{"echo", `a "b" $c`}},
+		{`echo "a \"b\" \n"`, []string{"echo", `a "b" \n`}},
+		{`a\ b c`, []string{"a b", "c"}},
+		{`--name=""`, []string{"--name="}},
+		{`"" x`, []string{"", "x"}},
+		{`["python", "-c", "print('a b')"]`, []string{"python", "-c", "print('a b')"}},
+	}
+	for _, c := range cases {
+		args, err := SplitCommandLine(c.text)
+		if err != nil {
+			t.Errorf("SplitCommandLine(%q): %s", c.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, c.expect) {
+			t.Errorf("SplitCommandLine(%q) = %q, expect %q", c.text, args, c.expect)
+		}
+	}
+	for _, text := range []string{`echo "a`, `echo 'a`, `["a", 1]`} {
+		if _, err := SplitCommandLine(text); err == nil {
+			t.Errorf("expect error for %q", text)
+		}
+	}
+}
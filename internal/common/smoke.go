@@ -0,0 +1,217 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+/*
+Smoke checks file declares checks to run after deploy, example smoke.toml:
+
+	retries = 3
+	interval = 5
+
+	[[checks]]
+	name = "health"
+	url = "https://api.example.com/health"
+	expect_status = 200
+	expect_body = '"status":\s*"ok"'
+
+	[[checks]]
+	name = "daily-task"
+	template = "timer"
+	expect_output = "success"
+
+Check with url sends http request, otherwise the function is invoked with
+one of payload, payload_file or template. The previous image is redeployed
+if any check failed.
+*/
+type SmokeCheckSpec struct {
+	Name    string `toml:"name"`
+	Timeout int    `toml:"timeout"` // http请求超时秒数，默认10
+	// http
+	Url          string            `toml:"url"` // 函数URL或自定义域名
+	Method       string            `toml:"method"`
+	Headers      map[string]string `toml:"headers"`
+	Body         string            `toml:"body"`
+	ExpectStatus int               `toml:"expect_status"` // 默认200
+	ExpectBody   string            `toml:"expect_body"`   // 响应内容正则
+	// invoke
+	Payload      string `toml:"payload"`
+	PayloadFile  string `toml:"payload_file"`
+	Template     string `toml:"template"`      // 内置事件模板: timer/apigw/cos
+	ExpectOutput string `toml:"expect_output"` // 函数返回内容正则
+}
+
+func (c SmokeCheckSpec) IsHTTP() bool {
+	return c.Url != ""
+}
+
+type SmokeConfig struct {
+	Retries  int              `toml:"retries"`  // 失败后重试次数，新实例启动可能需要时间
+	Interval int              `toml:"interval"` // 重试间隔秒数，默认5
+	Checks   []SmokeCheckSpec `toml:"checks"`
+}
+
+/* Invoke function with payload, used by invoke smoke checks */
+type SmokeInvoker func(payload string) (*InvokeResult, error)
+
+/*
+Load and validate smoke checks, payload of invoke checks is resolved here
+so that invalid payload file or template fails before build.
+*/
+func LoadSmokeChecks(filepath string, templates PayloadTemplates) (*SmokeConfig, error) {
+	data, err := ReadUserFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	var config SmokeConfig
+	err = toml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath, err)
+	}
+	var violations []string
+	for i, check := range config.Checks {
+		prefix := fmt.Sprintf("checks[%d]", i)
+		if check.Name == "" {
+			violations = append(violations, prefix+": name is required")
+		}
+		for _, pattern := range []string{check.ExpectBody, check.ExpectOutput} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %s", prefix, err))
+			}
+		}
+		numPayload := 0
+		for _, source := range []string{check.Payload, check.PayloadFile, check.Template} {
+			if source != "" {
+				numPayload += 1
+			}
+		}
+		if check.IsHTTP() {
+			if numPayload > 0 {
+				violations = append(violations, prefix+": payload can not be used with url")
+			}
+			continue
+		}
+		if check.ExpectBody != "" || check.ExpectStatus != 0 {
+			violations = append(violations, prefix+": expect_body and expect_status require url")
+		}
+		if numPayload > 1 {
+			violations = append(violations,
+				prefix+": only one of payload, payload_file and template can be used")
+			continue
+		}
+		if check.Payload == "" {
+			payload, err := LoadInvokePayload(check.PayloadFile, check.Template, templates)
+			if err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %s", prefix, err))
+				continue
+			}
+			config.Checks[i].Payload = payload
+		}
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("%s: invalid smoke checks:\n  - %s",
+			filepath, strings.Join(violations, "\n  - "))
+	}
+	return &config, nil
+}
+
+func _runHTTPSmokeCheck(check SmokeCheckSpec, timeout time.Duration) error {
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	request, err := http.NewRequest(method, check.Url, strings.NewReader(check.Body))
+	if err != nil {
+		return err
+	}
+	for k, v := range check.Headers {
+		request.Header.Set(k, v)
+	}
+	client := http.Client{Timeout: timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	expectStatus := check.ExpectStatus
+	if expectStatus <= 0 {
+		expectStatus = http.StatusOK
+	}
+	if response.StatusCode != expectStatus {
+		return fmt.Errorf("status %d, expect %d: %s",
+			response.StatusCode, expectStatus, _truncate(string(body), 200))
+	}
+	if check.ExpectBody != "" && !regexp.MustCompile(check.ExpectBody).Match(body) {
+		return fmt.Errorf("body not match %q: %s", check.ExpectBody, _truncate(string(body), 200))
+	}
+	return nil
+}
+
+func _runInvokeSmokeCheck(check SmokeCheckSpec, invoke SmokeInvoker) error {
+	result, err := invoke(check.Payload)
+	if err != nil {
+		return err
+	}
+	if result.Error != "" {
+		return fmt.Errorf("function error %s: %s", result.Error, _truncate(result.Body, 200))
+	}
+	if check.ExpectOutput != "" && !regexp.MustCompile(check.ExpectOutput).MatchString(result.Body) {
+		return fmt.Errorf("output not match %q: %s", check.ExpectOutput, _truncate(result.Body, 200))
+	}
+	return nil
+}
+
+func _truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	return text[:size] + "..."
+}
+
+/* Run smoke checks in order, return error of the first failed check */
+func RunSmokeChecks(config *SmokeConfig, invoke SmokeInvoker) error {
+	interval := time.Duration(config.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for _, check := range config.Checks {
+		timeout := time.Duration(check.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		var err error
+		for i := 0; i <= config.Retries; i++ {
+			if i > 0 {
+				log.Printf("[WARN] Smoke check %s failed, retry %d/%d: %s",
+					check.Name, i, config.Retries, err)
+				time.Sleep(interval)
+			}
+			if check.IsHTTP() {
+				err = _runHTTPSmokeCheck(check, timeout)
+			} else {
+				err = _runInvokeSmokeCheck(check, invoke)
+			}
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("smoke check %s failed: %s", check.Name, err)
+		}
+		log.Printf("[INFO] Smoke check %s passed", check.Name)
+	}
+	return nil
+}
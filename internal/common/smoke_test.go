@@ -0,0 +1,80 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSmokeChecks(t *testing.T) {
+	dir := t.TempDir()
+	payloadFile := filepath.Join(dir, "payload.json")
+	if err := os.WriteFile(payloadFile, []byte(`{"from": "file"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templates := PayloadTemplates{PAYLOAD_TEMPLATE_TIMER: `{"Type": "Timer"}`}
+	cases := []struct {
+		name    string
+		content string
+		payload string // 第一个检查解析后的payload
+		err     string // 错误信息包含的内容，为空表示成功
+	}{
+		{"default payload", "[[checks]]\nname = \"a\"", "{}", ""},
+		{"inline payload", "[[checks]]\nname = \"a\"\npayload = '{\"k\": 1}'", `{"k": 1}`, ""},
+		{"payload file", "[[checks]]\nname = \"a\"\npayload_file = '" + payloadFile + "'", `{"from": "file"}`, ""},
+		{"template", "[[checks]]\nname = \"a\"\ntemplate = \"timer\"", `{"Type": "Timer"}`, ""},
+		{"http", "[[checks]]\nname = \"a\"\nurl = \"http://localhost\"", "", ""},
+		{"missing name", "[[checks]]\ntemplate = \"timer\"", "", "name is required"},
+		{"invalid template", "[[checks]]\nname = \"a\"\ntemplate = \"cos\"", "", "invalid payload template"},
+		{"missing payload file", "[[checks]]\nname = \"a\"\npayload_file = '" +
+			filepath.Join(dir, "missing.json") + "'", "", "missing.json"},
+		{"payload and template", "[[checks]]\nname = \"a\"\npayload = \"{}\"\ntemplate = \"timer\"",
+			"", "only one of"},
+		{"payload and payload file", "[[checks]]\nname = \"a\"\npayload = \"{}\"\npayload_file = '" +
+			payloadFile + "'", "", "only one of"},
+		{"http with payload", "[[checks]]\nname = \"a\"\nurl = \"http://localhost\"\npayload = \"{}\"",
+			"", "can not be used with url"},
+		{"invoke with status", "[[checks]]\nname = \"a\"\nexpect_status = 200", "", "require url"},
+		{"invalid regexp", "[[checks]]\nname = \"a\"\nexpect_output = \"(\"", "", "missing closing"},
+	}
+	for i, c := range cases {
+		smokeFile := filepath.Join(dir, "smoke"+string(rune('a'+i))+".toml")
+		if err := os.WriteFile(smokeFile, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := LoadSmokeChecks(smokeFile, templates)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: error = %v, expect %q", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if config.Checks[0].Payload != c.payload {
+			t.Errorf("%s: payload = %q, expect %q", c.name, config.Checks[0].Payload, c.payload)
+		}
+	}
+}
+
+func TestRunSmokeChecksRetry(t *testing.T) {
+	config := &SmokeConfig{
+		Retries:  2,
+		Interval: 1,
+		Checks:   []SmokeCheckSpec{{Name: "a", Payload: "{}", ExpectOutput: "ok"}},
+	}
+	calls := 0
+	err := RunSmokeChecks(config, func(payload string) (*InvokeResult, error) {
+		calls += 1
+		if calls < 2 {
+			return &InvokeResult{Body: "starting"}, nil
+		}
+		return &InvokeResult{Body: "ok"}, nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("RunSmokeChecks err=%v calls=%d", err, calls)
+	}
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+/*
+Triggers file declares triggers of function, example triggers.toml:
+
+	[[triggers]]
+	name = "daily-cleanup"
+	type = "timer"
+	cron = "0 0 2 * * * *"
+	payload = '{"task": "cleanup"}'
+
+	[[triggers]]
+	name = "url"
+	type = "http"
+	auth_type = "NONE"
+
+Triggers not declared are kept, unless prune is specified.
+*/
+const (
+	TRIGGER_TIMER string = "timer"
+	TRIGGER_APIGW string = "apigw"
+	TRIGGER_HTTP  string = "http" // 函数URL
+	TRIGGER_COS   string = "cos"
+	TRIGGER_CMQ   string = "cmq"
+)
+
+// 通过触发器描述区分ezfaas管理的触发器
+const TRIGGER_DESCRIPTION string = "managed by ezfaas"
+
+const (
+	TRIGGER_ACTION_CREATE string = "create"
+	TRIGGER_ACTION_UPDATE string = "update"
+	TRIGGER_ACTION_DELETE string = "delete"
+	TRIGGER_ACTION_KEEP   string = "keep" // 未声明且不由ezfaas管理，保留不变
+)
+
+type TriggerSpec struct {
+	Name      string `toml:"name"`
+	Type      string `toml:"type"`
+	Enabled   *bool  `toml:"enabled"`   // 默认启用，阿里云HTTP触发器不支持禁用
+	Qualifier string `toml:"qualifier"` // 函数版本或别名，默认$DEFAULT
+	Payload   string `toml:"payload"`   // 定时触发器的附加信息
+	// timer
+	Cron string `toml:"cron"`
+	// apigw
+	Service      string `toml:"service"` // API网关服务ID
+	Method       string `toml:"method"`
+	Environment  string `toml:"environment"`
+	AuthRequired bool   `toml:"auth_required"`
+	// http
+	AuthType string   `toml:"auth_type"` // 腾讯云: NONE 或 CAM，阿里云: anonymous 或 function
+	Intranet bool     `toml:"intranet"`  // 是否开启内网访问
+	Methods  []string `toml:"methods"`   // 阿里云HTTP触发器允许的方法
+	// cos
+	Bucket string `toml:"bucket"` // 存储桶访问域名
+	Event  string `toml:"event"`
+	Prefix string `toml:"prefix"`
+	Suffix string `toml:"suffix"`
+	// cmq
+	Topic string `toml:"topic"`
+	// 平台原始的触发器配置，优先于以上参数
+	Desc string `toml:"desc"`
+}
+
+func (t TriggerSpec) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+type TriggersConfig struct {
+	Triggers []TriggerSpec `toml:"triggers"`
+}
+
+func LoadTriggers(filepath string) (*TriggersConfig, error) {
+	data, err := ReadUserFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	var config TriggersConfig
+	err = toml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath, err)
+	}
+	var violations []string
+	names := map[string]bool{}
+	for i, trigger := range config.Triggers {
+		if trigger.Name == "" {
+			violations = append(violations, fmt.Sprintf("triggers[%d]: name is required", i))
+		}
+		if trigger.Type == "" {
+			violations = append(violations, fmt.Sprintf("triggers[%d]: type is required", i))
+		}
+		key := trigger.Type + "/" + trigger.Name
+		if names[key] {
+			violations = append(violations, fmt.Sprintf("triggers[%d]: duplicated %s", i, key))
+		}
+		names[key] = true
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("%s: invalid triggers:\n  - %s",
+			filepath, strings.Join(violations, "\n  - "))
+	}
+	return &config, nil
+}
+
+/* Change of trigger plan, key is unique in the platform */
+type TriggerChange struct {
+	Action string
+	Key    string
+	Detail string // 新的触发器配置，为空表示不输出
+}
+
+func (c TriggerChange) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", c.Action, c.Key, c.Detail))
+}
+
+/* Action and key of changes in order, used to compare plans */
+func SummaryTriggerChanges(changes []TriggerChange) []string {
+	var result []string
+	for _, change := range changes {
+		result = append(result, change.Action+" "+change.Key)
+	}
+	return result
+}
+
+func LogTriggerChanges(changes []TriggerChange) {
+	hasChanges := false
+	for _, change := range changes {
+		log.Printf("[INFO] Trigger %s", change.String())
+		if change.Action != TRIGGER_ACTION_KEEP {
+			hasChanges = true
+		}
+	}
+	if !hasChanges {
+		log.Printf("[INFO] Triggers no changes")
+	}
+}
+
+/* Undeclared triggers are deleted if managed by ezfaas, others only if prune */
+func IsTriggerRemovable(description string, prune bool) bool {
+	return prune || description == TRIGGER_DESCRIPTION
+}
@@ -3,59 +3,245 @@ package internal
 import (
 	"fmt"
 	"log"
+	"os"
 
-	"github.com/BurntSushi/toml"
 	"github.com/guyskk/ezfaas/internal/aliyun"
 	"github.com/guyskk/ezfaas/internal/common"
 	"github.com/guyskk/ezfaas/internal/tencent"
-	"github.com/joho/godotenv"
+)
+
+const (
+	PROVIDER_TENCENT string = "tencent"
+	PROVIDER_ALIYUN  string = "aliyun"
 )
 
 type BaseDeployParams struct {
 	BaseBuildParams
 	FunctionName string
-	Envfile      string
+	EnvfileList  []string
+	EnvKeyFile   string
+	EnvSchema    string
+	EnvMode      string
+	EnvList      []string
+	UnsetEnvList []string
 	Repository   string
 	BuildId      string
+	SmokeFile    string // 冒烟测试声明文件，失败时回滚镜像和环境变量
+	ImagePort    int    // 容器监听端口，0表示不修改
+	Command      string // 容器启动命令，为空表示不修改
+	Args         string // 容器启动参数，为空表示不修改
+	Entrypoint   string // 为空表示不修改
 	Yes          bool
 }
 
 type AliyunDeployParams struct {
 	BaseDeployParams
+	HealthCheck aliyun.HealthCheckParams
+	// 触发器声明文件，为空表示不管理触发器
+	TriggersFile  string
+	PruneTriggers bool
 }
 
 type TencentDeployParams struct {
 	BaseDeployParams
 	Region string
 	IsJob  bool
+	// 企业版镜像仓库和镜像加速
+	ImageType       string
+	RegistryId      string
+	ImageAccelerate string
+	// 触发器声明文件，为空表示不管理触发器
+	TriggersFile  string
+	PruneTriggers bool
+	// 部署成功后刷新的CDN域名
+	CDNPurgeDomainList []string
+	CDNPurge           CDNPurgeParams
 }
 
-func _readEnvfile(envfile string) *map[string]string {
-	tomlLoads := toml.Unmarshal
-	if tomlLoads == nil {
-		fmt.Print("")
+/*
+Read envfiles and merge them in order. ${VAR} can reference variables of
+previous envfiles, build metadata and process environment. Names of variables
+from encrypted envfiles are returned as secret names.
+*/
+func _readEnvfile(
+	envfileList []string,
+	envKeyFile string,
+	buildInfo BuildInfo,
+) (*map[string]string, []string) {
+	if len(envfileList) <= 0 {
+		return nil, nil
+	}
+	env := map[string]string{}
+	var secretNames []string
+	buildEnv := map[string]string{
+		"EZFAAS_BUILD_ID":  buildInfo.BuildId,
+		"EZFAAS_COMMIT_ID": buildInfo.CommitId,
+	}
+	lookup := func(key string) (string, bool) {
+		if v, ok := env[key]; ok {
+			return v, true
+		}
+		if v, ok := buildEnv[key]; ok {
+			return v, true
+		}
+		return os.LookupEnv(key)
 	}
-	var env *map[string]string = nil
-	if envfile != "" {
+	var envKey *common.EnvKey = nil
+	for _, envfile := range envfileList {
 		envdata, err := common.ReadUserFile(envfile)
 		if err != nil {
 			log.Fatal(err)
 		}
-		_env, err := godotenv.Unmarshal(string(envdata))
+		content := string(envdata)
+		isEncrypted := common.IsEncryptedEnvfile(content)
+		if isEncrypted {
+			if envKey == nil {
+				envKey, err = common.LoadEnvKey(envKeyFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			content, err = common.DecryptEnvfile(content, envKey)
+			if err != nil {
+				log.Fatalf("%s: %s", envfile, err)
+			}
+		}
+		fileEnv, err := common.ParseEnvfile(content, lookup)
+		if err != nil {
+			log.Fatalf("%s: %s", envfile, err)
+		}
+		for k, v := range fileEnv {
+			env[k] = v
+			if isEncrypted {
+				secretNames = append(secretNames, k)
+			}
+		}
+	}
+	return &env, secretNames
+}
+
+/* Load env schema, use env.schema.toml if exists and not specified */
+func _loadEnvSchema(filepath string) *common.EnvSchema {
+	if filepath == "" {
+		if _, err := os.Stat(common.DEFAULT_ENV_SCHEMA_FILE); err != nil {
+			return nil
+		}
+		filepath = common.DEFAULT_ENV_SCHEMA_FILE
+	}
+	schema, err := common.LoadEnvSchema(filepath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("[INFO] EnvSchema=%s", filepath)
+	return schema
+}
+
+func _prepareBuildInfo(params BaseDeployParams) BuildInfo {
+	if params.BuildId != "" {
+		return GetExistedBuildInfo(params.BuildId)
+	}
+	return NewBuildInfo()
+}
+
+func _makeSecretResolvers(
+	tencentRegion string,
+	aliyunRegion string,
+) map[string]common.SecretResolver {
+	return map[string]common.SecretResolver{
+		"ssm":        tencent.SSMSecretResolver{Region: tencentRegion},
+		"kms":        aliyun.KMSSecretResolver{Region: aliyunRegion},
+		"secretfile": common.FileSecretResolver{},
+	}
+}
+
+/* Secret resolvers use the region of provider by default */
+func _makeProviderSecretResolvers(provider string, region string) map[string]common.SecretResolver {
+	switch provider {
+	case PROVIDER_TENCENT:
+		return _makeSecretResolvers(region, "")
+	case PROVIDER_ALIYUN:
+		return _makeSecretResolvers("", region)
+	}
+	return _makeSecretResolvers("", "")
+}
+
+func _prepareEnvUpdate(
+	params BaseDeployParams,
+	buildInfo BuildInfo,
+	resolvers map[string]common.SecretResolver,
+	rules common.EnvRules,
+) *common.EnvUpdate {
+	params.EnvMode = common.GetEnvMode(params.EnvMode, len(params.EnvfileList) > 0)
+	if !common.IsValidEnvMode(params.EnvMode) {
+		log.Fatalf("invalid env mode %q", params.EnvMode)
+	}
+	if params.EnvMode == common.ENV_MODE_PATCH && len(params.EnvfileList) > 0 {
+		log.Fatalf("env mode %s not support envfile", params.EnvMode)
+	}
+	if params.EnvMode == common.ENV_MODE_REPLACE && len(params.EnvfileList) <= 0 {
+		log.Fatalf("env mode %s requires envfile, use %s or %s to keep other variables",
+			params.EnvMode, common.ENV_MODE_MERGE, common.ENV_MODE_PATCH)
+	}
+	setEnv, err := common.ParseEnvAssignments(params.EnvList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	env, envfileSecretNames := _readEnvfile(params.EnvfileList, params.EnvKeyFile, buildInfo)
+	if env == nil && len(params.EnvList) <= 0 && len(params.UnsetEnvList) <= 0 {
+		return nil
+	}
+	update := common.EnvUpdate{
+		Mode:      params.EnvMode,
+		UnsetList: params.UnsetEnvList,
+		Schema:    _loadEnvSchema(params.EnvSchema),
+		// 加密envfile中的变量都是密钥，输出时隐藏
+		SecretNames: envfileSecretNames,
+	}
+	var secretNames []string
+	if env != nil {
+		update.Variables, secretNames, err = common.ResolveSecretEnv(*env, resolvers)
 		if err != nil {
 			log.Fatal(err)
 		}
-		env = &_env
+		update.SecretNames = append(update.SecretNames, secretNames...)
+	}
+	update.SetEnv, secretNames, err = common.ResolveSecretEnv(setEnv, resolvers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	update.SecretNames = append(update.SecretNames, secretNames...)
+	// 在构建镜像前检查环境变量，合并模式下函数当前的变量在部署时再检查
+	newEnv := map[string]string{}
+	for k, v := range update.Variables {
+		newEnv[k] = v
+	}
+	for k, v := range update.SetEnv {
+		newEnv[k] = v
+	}
+	err = common.ValidateEnv(rules, newEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for k, v := range common.GetEnvSchemaDefaults(update.Schema) {
+		if _, ok := newEnv[k]; !ok {
+			newEnv[k] = v
+		}
 	}
-	return env
+	isReplace := update.Mode == common.ENV_MODE_REPLACE
+	err = common.CheckEnvSchema(update.Schema, newEnv, isReplace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &update
 }
 
-func _prepareImage(params BaseDeployParams) string {
+func _prepareImage(params BaseDeployParams, buildInfo BuildInfo) string {
 	buildId := params.BuildId
 	if buildId == "" {
 		buildResult, err := Build(BuildParams{
 			BaseBuildParams: params.BaseBuildParams,
 			Repository:      params.Repository,
+			BuildInfo:       &buildInfo,
 		})
 		if err != nil {
 			log.Fatal(err)
@@ -71,15 +257,51 @@ func _prepareImage(params BaseDeployParams) string {
 	return buildId
 }
 
+func _loadTriggers(triggersFile string) *common.TriggersConfig {
+	if triggersFile == "" {
+		return nil
+	}
+	triggers, err := common.LoadTriggers(triggersFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return triggers
+}
+
+func _loadSmokeChecks(smokeFile string, templates common.PayloadTemplates) *common.SmokeConfig {
+	if smokeFile == "" {
+		return nil
+	}
+	config, err := common.LoadSmokeChecks(smokeFile, templates)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return config
+}
+
 func DoDeployAliyun(params AliyunDeployParams) {
-	env := _readEnvfile(params.Envfile)
-	buildId := _prepareImage(params.BaseDeployParams)
+	if params.ImagePort < 0 {
+		log.Fatalf("invalid image port %d", params.ImagePort)
+	}
+	container := _getAliyunContainerParams(params)
+	buildInfo := _prepareBuildInfo(params.BaseDeployParams)
+	region, _ := aliyun.GetRegionFromRepository(params.Repository)
+	resolvers := _makeSecretResolvers("", region)
+	envUpdate := _prepareEnvUpdate(
+		params.BaseDeployParams, buildInfo, resolvers, aliyun.EnvRules)
+	triggers := _loadTriggers(params.TriggersFile)
+	smokeChecks := _loadSmokeChecks(params.SmokeFile, aliyun.PayloadTemplates)
+	buildId := _prepareImage(params.BaseDeployParams, buildInfo)
 	output, err := aliyun.DoDeploy(aliyun.DeployParams{
-		FunctionName:         params.FunctionName,
-		Repository:           params.Repository,
-		Yes:                  params.Yes,
-		BuildId:              buildId,
-		EnvironmentVariables: env,
+		FunctionName:  params.FunctionName,
+		Repository:    params.Repository,
+		Yes:           params.Yes,
+		BuildId:       buildId,
+		EnvUpdate:     envUpdate,
+		Triggers:      triggers,
+		PruneTriggers: params.PruneTriggers,
+		SmokeChecks:   smokeChecks,
+		Container:     container,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -87,27 +309,90 @@ func DoDeployAliyun(params AliyunDeployParams) {
 	common.LogPrettyJSON(output)
 }
 
+/*
+Command, args and entrypoint are JSON array or shell style command line.
+Command is the same as entrypoint for aliyun, args replace current args.
+*/
+func _getAliyunContainerParams(params AliyunDeployParams) aliyun.ContainerParams {
+	container := aliyun.ContainerParams{
+		Port:        int32(params.ImagePort),
+		HealthCheck: params.HealthCheck,
+	}
+	if params.Command != "" && params.Entrypoint != "" {
+		log.Fatal("command and entrypoint can not be used together for aliyun")
+	}
+	entrypoint := params.Entrypoint
+	if params.Command != "" {
+		entrypoint = params.Command
+	}
+	var err error
+	if entrypoint != "" {
+		container.Entrypoint, err = common.SplitCommandLine(entrypoint)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if params.Args != "" {
+		container.Args, err = common.SplitCommandLine(params.Args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	return container
+}
+
 func DoDeployTencent(params TencentDeployParams) {
-	env := _readEnvfile(params.Envfile)
-	buildId := _prepareImage(params.BaseDeployParams)
+	if params.ImagePort < 0 {
+		log.Fatalf("invalid image port %d", params.ImagePort)
+	}
+	if params.IsJob && params.ImagePort > 0 {
+		log.Fatal("job function has no image port")
+	}
+	buildInfo := _prepareBuildInfo(params.BaseDeployParams)
+	resolvers := _makeSecretResolvers(params.Region, "")
+	envUpdate := _prepareEnvUpdate(
+		params.BaseDeployParams, buildInfo, resolvers, tencent.EnvRules)
+	triggers := _loadTriggers(params.TriggersFile)
+	smokeChecks := _loadSmokeChecks(params.SmokeFile, tencent.PayloadTemplates)
+	buildId := _prepareImage(params.BaseDeployParams, buildInfo)
 	var imagePort *int64
 	var jobImagePort int64 = -1
+	var customImagePort int64 = int64(params.ImagePort)
 	if params.IsJob {
 		imagePort = &jobImagePort
+	} else if params.ImagePort > 0 {
+		imagePort = &customImagePort
 	} else {
 		imagePort = nil
 	}
 	output, err := tencent.DoDeploy(tencent.DeployParams{
-		Region:               params.Region,
-		FunctionName:         params.FunctionName,
-		Repository:           params.Repository,
-		Yes:                  params.Yes,
-		ImagePort:            imagePort,
-		BuildId:              buildId,
-		EnvironmentVariables: env,
+		Region:          params.Region,
+		FunctionName:    params.FunctionName,
+		Repository:      params.Repository,
+		Yes:             params.Yes,
+		ImagePort:       imagePort,
+		BuildId:         buildId,
+		EnvUpdate:       envUpdate,
+		Triggers:        triggers,
+		PruneTriggers:   params.PruneTriggers,
+		SmokeChecks:     smokeChecks,
+		Command:         params.Command,
+		Args:            params.Args,
+		EntryPoint:      params.Entrypoint,
+		ImageType:       params.ImageType,
+		RegistryId:      params.RegistryId,
+		ImageAccelerate: params.ImageAccelerate,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	common.LogPrettyJSON(output)
+	for _, domain := range params.CDNPurgeDomainList {
+		purgeParams := params.CDNPurge
+		purgeParams.Domain = domain
+		err := _purgeCDNCache(purgeParams)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 }
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"log"
+
+	"github.com/guyskk/ezfaas/internal/aliyun"
+	"github.com/guyskk/ezfaas/internal/common"
+)
+
+type AliyunCustomDomainParams struct {
+	Region     string
+	ConfigFile string
+	Yes        bool
+}
+
+func DoConfigCustomDomainAliyun(params AliyunCustomDomainParams) {
+	customDomains, err := common.LoadCustomDomains(params.ConfigFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = aliyun.UpdateCustomDomains(aliyun.CustomDomainParams{
+		Region:        params.Region,
+		CustomDomains: customDomains,
+		Yes:           params.Yes,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/guyskk/ezfaas/internal/common"
+	"github.com/mitchellh/go-homedir"
+)
+
+type EnvKeygenParams struct {
+	KeyFile string
+}
+
+type EnvCryptParams struct {
+	Envfile   string
+	KeyFile   string
+	Recipient string
+}
+
+type EnvCheckParams struct {
+	EnvfileList []string
+	KeyFile     string
+	Schema      string
+	Provider    string // 解析密钥引用使用的云厂商和地域
+	Region      string
+}
+
+type EnvDiffParams struct {
+	From    string
+	To      string
+	KeyFile string
+}
+
+func DoEnvKeygen(params EnvKeygenParams) {
+	keyFile := params.KeyFile
+	if keyFile == "" {
+		keyFile = common.DEFAULT_ENV_KEY_FILE
+	}
+	if _, err := common.ReadUserFile(keyFile); err == nil {
+		log.Fatalf("key file %s already exists", keyFile)
+	}
+	envKey, err := common.GenerateEnvKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	keyPath, err := homedir.Expand(keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 默认密钥文件目录~/.config可能不存在
+	err = os.MkdirAll(filepath.Dir(keyPath), 0700)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = common.WriteUserFile(keyFile, []byte(envKey.SecretKeyString()+"\n"), 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("[INFO] KeyFile=%s", keyFile)
+	log.Printf("[INFO] PublicKey=%s", envKey.PublicKeyString())
+}
+
+func _getEnvRecipient(params EnvCryptParams, content string) string {
+	if params.Recipient != "" {
+		return params.Recipient
+	}
+	recipient := common.GetEnvfileRecipient(content)
+	if recipient != "" {
+		return recipient
+	}
+	envKey, err := common.LoadEnvKey(params.KeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return envKey.PublicKeyString()
+}
+
+func _writeEnvfile(envfile string, content string) {
+	perm := os.FileMode(0644)
+	if path, err := homedir.Expand(envfile); err == nil {
+		if fileinfo, err := os.Stat(path); err == nil {
+			perm = fileinfo.Mode().Perm()
+		}
+	}
+	err := common.WriteUserFile(envfile, []byte(content), perm)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func DoEnvEncrypt(params EnvCryptParams) {
+	data, err := common.ReadUserFile(params.Envfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	content := string(data)
+	recipient := _getEnvRecipient(params, content)
+	encrypted, err := common.EncryptEnvfile(content, recipient)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_writeEnvfile(params.Envfile, encrypted)
+	log.Printf("[INFO] Encrypted %s Recipient=%s", params.Envfile, recipient)
+}
+
+func DoEnvDecrypt(params EnvCryptParams) {
+	data, err := common.ReadUserFile(params.Envfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	envKey, err := common.LoadEnvKey(params.KeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	decrypted, err := common.DecryptEnvfile(string(data), envKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(decrypted)
+}
+
+func _runEditor(filepath string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command(editor, filepath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+/* Edit content in a temp file, the temp file is always removed */
+func _editInTempFile(content string) (string, error) {
+	tempFile, err := os.CreateTemp("", "ezfaas-*.env")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.WriteString(content)
+	tempFile.Close()
+	if err != nil {
+		return "", err
+	}
+	err = _runEditor(tempFile.Name())
+	if err != nil {
+		return "", err
+	}
+	edited, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
+
+/* Decrypt envfile to a temp file, edit it and encrypt it back */
+func DoEnvEdit(params EnvCryptParams) {
+	data, err := common.ReadUserFile(params.Envfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	content := string(data)
+	recipient := _getEnvRecipient(params, content)
+	if common.IsEncryptedEnvfile(content) {
+		envKey, err := common.LoadEnvKey(params.KeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		content, err = common.DecryptEnvfile(content, envKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	edited, err := _editInTempFile(content)
+	if err != nil {
+		log.Fatal(err)
+	}
+	encrypted, err := common.EncryptEnvfile(edited, recipient)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_writeEnvfile(params.Envfile, encrypted)
+	log.Printf("[INFO] Encrypted %s Recipient=%s", params.Envfile, recipient)
+}
+
+func DoEnvCheck(params EnvCheckParams) {
+	schemaFile := params.Schema
+	if schemaFile == "" {
+		schemaFile = common.DEFAULT_ENV_SCHEMA_FILE
+	}
+	schema, err := common.LoadEnvSchema(schemaFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	envfileEnv, _ := _readEnvfile(params.EnvfileList, params.KeyFile, NewBuildInfo())
+	env := *envfileEnv
+	// 与部署一致，先解析密钥引用再检查类型
+	resolvers := _makeProviderSecretResolvers(params.Provider, params.Region)
+	env, _, err = common.ResolveSecretEnv(env, resolvers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for k, v := range common.GetEnvSchemaDefaults(schema) {
+		if _, ok := env[k]; !ok {
+			env[k] = v
+		}
+	}
+	err = common.CheckEnvSchema(schema, env, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("[INFO] Envfile check passed, %d variables", len(env))
+}
+
+func DoEnvDiff(params EnvDiffParams) {
+	buildInfo := NewBuildInfo()
+	oldEnv, _ := _readEnvfile([]string{params.From}, params.KeyFile, buildInfo)
+	newEnv, _ := _readEnvfile([]string{params.To}, params.KeyFile, buildInfo)
+	diff := common.DiffEnvKeys(*oldEnv, *newEnv)
+	for _, name := range diff.Removed {
+		fmt.Printf("- %s\n", name)
+	}
+	for _, name := range diff.Added {
+		fmt.Printf("+ %s\n", name)
+	}
+	for _, name := range diff.Changed {
+		fmt.Printf("~ %s\n", name)
+	}
+}
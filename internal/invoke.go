@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"log"
+
+	"github.com/guyskk/ezfaas/internal/aliyun"
+	"github.com/guyskk/ezfaas/internal/common"
+	"github.com/guyskk/ezfaas/internal/tencent"
+)
+
+type InvokeParams struct {
+	Provider     string
+	Region       string
+	FunctionName string
+	Qualifier    string
+	PayloadFile  string
+	Template     string // 内置事件模板: timer/apigw/cos
+	Mode         string
+}
+
+func DoInvoke(params InvokeParams) {
+	if params.Mode != common.INVOKE_MODE_SYNC && params.Mode != common.INVOKE_MODE_ASYNC {
+		log.Fatalf("invalid invoke mode %q, expect %s or %s",
+			params.Mode, common.INVOKE_MODE_SYNC, common.INVOKE_MODE_ASYNC)
+	}
+	var result *common.InvokeResult
+	var err error
+	switch params.Provider {
+	case PROVIDER_TENCENT:
+		payload, payloadErr := common.LoadInvokePayload(
+			params.PayloadFile, params.Template, tencent.PayloadTemplates)
+		if payloadErr != nil {
+			log.Fatal(payloadErr)
+		}
+		result, err = tencent.Invoke(tencent.InvokeParams{
+			Region:       params.Region,
+			FunctionName: params.FunctionName,
+			Qualifier:    params.Qualifier,
+			Payload:      payload,
+			Mode:         params.Mode,
+		})
+	case PROVIDER_ALIYUN:
+		payload, payloadErr := common.LoadInvokePayload(
+			params.PayloadFile, params.Template, aliyun.PayloadTemplates)
+		if payloadErr != nil {
+			log.Fatal(payloadErr)
+		}
+		result, err = aliyun.Invoke(aliyun.InvokeParams{
+			Region:       params.Region,
+			FunctionName: params.FunctionName,
+			Qualifier:    params.Qualifier,
+			Payload:      payload,
+			Mode:         params.Mode,
+		})
+	default:
+		log.Fatalf("invalid provider %q, expect %s or %s",
+			params.Provider, PROVIDER_TENCENT, PROVIDER_ALIYUN)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	common.PrintInvokeResult(result)
+	if result.Error != "" {
+		log.Fatal("function invoke failed")
+	}
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/guyskk/ezfaas/internal/tencent"
+)
+
+type JobRunParams struct {
+	Region       string
+	FunctionName string
+	Qualifier    string
+	ArgList      []string
+	Timeout      time.Duration
+}
+
+func DoJobRun(params JobRunParams) {
+	args := params.ArgList
+	if args == nil {
+		args = []string{}
+	}
+	payload, err := json.Marshal(map[string]interface{}{"args": args})
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := tencent.RunJob(tencent.JobRunParams{
+		Region:       params.Region,
+		FunctionName: params.FunctionName,
+		Qualifier:    params.Qualifier,
+		Payload:      string(payload),
+		Timeout:      params.Timeout,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !result.IsSuccess() {
+		log.Fatalf("job failed RequestId=%s RetCode=%d Duration=%.2fms: %s",
+			result.RequestId, result.RetCode, result.Duration, result.RetMsg)
+	}
+	log.Printf("[INFO] Job succeeded RequestId=%s Duration=%.2fms",
+		result.RequestId, result.Duration)
+	if result.RetMsg != "" {
+		log.Printf("[INFO] Job output: %s", result.RetMsg)
+	}
+}
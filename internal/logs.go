@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"log"
+	"time"
+
+	"github.com/guyskk/ezfaas/internal/aliyun"
+	"github.com/guyskk/ezfaas/internal/common"
+	"github.com/guyskk/ezfaas/internal/tencent"
+)
+
+type LogsParams struct {
+	Provider      string
+	Region        string
+	FunctionName  string
+	Qualifier     string
+	Since         time.Duration
+	Follow        bool
+	Filter        string
+	SinceModified bool // 只显示函数最近一次修改之后的日志，不区分版本
+}
+
+func DoLogs(params LogsParams) {
+	var fetch common.LogFetcher
+	var getLastModifiedTime func() (time.Time, error)
+	var err error
+	switch params.Provider {
+	case PROVIDER_TENCENT:
+		logsParams := tencent.LogsParams{
+			Region:       params.Region,
+			FunctionName: params.FunctionName,
+			Qualifier:    params.Qualifier,
+		}
+		fetch, err = tencent.GetLogFetcher(logsParams)
+		getLastModifiedTime = func() (time.Time, error) {
+			return tencent.GetLastModifiedTime(logsParams)
+		}
+	case PROVIDER_ALIYUN:
+		logsParams := aliyun.LogsParams{
+			Region:       params.Region,
+			FunctionName: params.FunctionName,
+			Qualifier:    params.Qualifier,
+		}
+		fetch, err = aliyun.GetLogFetcher(logsParams)
+		getLastModifiedTime = func() (time.Time, error) {
+			return aliyun.GetLastModifiedTime(logsParams)
+		}
+	default:
+		log.Fatalf("invalid provider %q, expect %s or %s",
+			params.Provider, PROVIDER_TENCENT, PROVIDER_ALIYUN)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	since := time.Now().Add(-params.Since)
+	if params.SinceModified {
+		modifiedTime, err := getLastModifiedTime()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("[INFO] Function modified at %s", modifiedTime.Local().Format(time.RFC3339))
+		if modifiedTime.After(since) {
+			since = modifiedTime
+		}
+	}
+	err = common.TailLogs(common.TailLogsParams{
+		Since:  since,
+		Follow: params.Follow,
+		Filter: params.Filter,
+	}, fetch)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,7 +3,9 @@ package internal
 import (
 	"log"
 	"os"
+	"time"
 
+	"github.com/guyskk/ezfaas/internal/common"
 	"github.com/spf13/cobra"
 )
 
@@ -37,8 +39,28 @@ func _AddBaseDeployFlags(cmd *cobra.Command, params *BaseDeployParams) {
 	_AddBaseBuildFlags(cmd, &params.BaseBuildParams)
 	cmd.Flags().StringVar(
 		&params.BuildId, "build-id", "", "Existed build id (image version)")
+	cmd.Flags().StringArrayVar(
+		&params.EnvfileList, "envfile", []string{}, "Envfile path, merged in order")
+	cmd.Flags().StringVar(
+		&params.EnvKeyFile, "env-key-file", "", "Secret key file to decrypt envfile")
+	cmd.Flags().StringVar(
+		&params.EnvSchema, "env-schema", "", "Env schema file, default env.schema.toml if exists")
+	cmd.Flags().StringVar(
+		&params.EnvMode, "env-mode", "", "Env update mode: replace/merge/patch, default replace with envfile, otherwise patch")
+	cmd.Flags().StringArrayVar(
+		&params.EnvList, "env", []string{}, "Set env variable KEY=VALUE")
+	cmd.Flags().StringArrayVar(
+		&params.UnsetEnvList, "unset-env", []string{}, "Unset env variable KEY")
 	cmd.Flags().StringVar(
-		&params.Envfile, "envfile", "", "Envfile path")
+		&params.SmokeFile, "smoke", "", "Smoke checks TOML file, rollback image and env if failed")
+	cmd.Flags().IntVar(
+		&params.ImagePort, "image-port", 0, "Container listen port, default unchanged")
+	cmd.Flags().StringVar(
+		&params.Command, "command", "", "Container start command, default unchanged, same as entrypoint for aliyun")
+	cmd.Flags().StringVar(
+		&params.Args, "args", "", "Container start args, default unchanged, replace current args")
+	cmd.Flags().StringVar(
+		&params.Entrypoint, "entrypoint", "", "Container entrypoint, default unchanged, JSON array or shell style for aliyun")
 	cmd.Flags().BoolVar(
 		&params.Yes, "yes", false, "Confirm deploy")
 }
@@ -53,6 +75,208 @@ func _MakeDeployAliyunCommand() *cobra.Command {
 		},
 	}
 	_AddBaseDeployFlags(&cmd, &params.BaseDeployParams)
+	_AddTriggersFlags(&cmd, &params.TriggersFile, &params.PruneTriggers)
+	cmd.Flags().StringVar(
+		&params.HealthCheck.Url, "health-check-url", "", "Health check http path, eg: /health")
+	cmd.Flags().Int32Var(
+		&params.HealthCheck.InitialDelaySeconds, "health-check-initial-delay", 0, "Health check initial delay seconds")
+	cmd.Flags().Int32Var(
+		&params.HealthCheck.PeriodSeconds, "health-check-period", 5, "Health check period seconds")
+	cmd.Flags().Int32Var(
+		&params.HealthCheck.TimeoutSeconds, "health-check-timeout", 3, "Health check timeout seconds")
+	cmd.Flags().Int32Var(
+		&params.HealthCheck.FailureThreshold, "health-check-failure-threshold", 3, "Health check failure threshold")
+	cmd.Flags().Int32Var(
+		&params.HealthCheck.SuccessThreshold, "health-check-success-threshold", 1, "Health check success threshold")
+	return &cmd
+}
+
+func _AddTriggersFlags(cmd *cobra.Command, triggersFile *string, pruneTriggers *bool) {
+	cmd.Flags().StringVar(
+		triggersFile, "triggers", "", "Triggers TOML file, triggers are not managed if not specified")
+	cmd.Flags().BoolVar(
+		pruneTriggers, "prune-triggers", false, "Delete undeclared triggers not managed by ezfaas")
+}
+
+func _MakeRunCommand() *cobra.Command {
+	var params RunParams
+	cmd := cobra.Command{
+		Use:   "run",
+		Short: "Build and run function image locally with platform emulation",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoRun(params)
+		},
+	}
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVar(
+		&params.Provider, "provider", PROVIDER_TENCENT, "Platform to emulate: tencent/aliyun")
+	cmd.Flags().StringVar(
+		&params.FunctionName, "function", "local", "Function name")
+	cmd.Flags().StringVar(
+		&params.Region, "region", "", "Region name")
+	cmd.Flags().StringVar(
+		&params.Repository, "repository", "ezfaas-local", "Docker image repository")
+	_AddBaseBuildFlags(&cmd, &params.BaseBuildParams)
+	cmd.Flags().StringVar(
+		&params.BuildId, "build-id", "", "Existed build id (image version)")
+	cmd.Flags().StringArrayVar(
+		&params.EnvfileList, "envfile", []string{}, "Envfile path, merged in order")
+	cmd.Flags().StringVar(
+		&params.EnvKeyFile, "env-key-file", "", "Secret key file to decrypt envfile")
+	cmd.Flags().StringVar(
+		&params.EnvSchema, "env-schema", "", "Env schema file, default env.schema.toml if exists")
+	cmd.Flags().StringArrayVar(
+		&params.EnvList, "env", []string{}, "Set env variable KEY=VALUE")
+	cmd.Flags().IntVar(
+		&params.MemorySize, "memory", 512, "Memory limit in MB")
+	cmd.Flags().IntVar(
+		&params.Timeout, "timeout", 30, "Function timeout in seconds")
+	cmd.Flags().IntVar(
+		&params.Port, "port", 0, "Container port, default 9000 of SCF and FC CAPort")
+	cmd.Flags().IntVar(
+		&params.HostPort, "host-port", 0, "Local port, default same as container port")
+	cmd.Flags().BoolVar(
+		&params.IsEvent, "event", false, "Is event function, expose local invoke endpoint")
+	cmd.Flags().IntVar(
+		&params.InvokePort, "invoke-port", 9001, "Local invoke endpoint port of event function")
+	return &cmd
+}
+
+func _MakeServeLocalCommand() *cobra.Command {
+	var params ServeLocalParams
+	cmd := cobra.Command{
+		Use:   "serve-local",
+		Short: "Serve local api gateway for event function running locally",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoServeLocal(params)
+		},
+	}
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVar(
+		&params.Provider, "provider", PROVIDER_TENCENT, "Platform to emulate: tencent/aliyun")
+	cmd.Flags().IntVar(
+		&params.Port, "port", 8080, "Local api gateway port")
+	cmd.Flags().StringVar(
+		&params.ContainerUrl, "container-url", "http://127.0.0.1:9000", "Url of function container")
+	cmd.Flags().StringVar(
+		&params.FunctionName, "function", "local", "Function name")
+	cmd.Flags().StringVar(
+		&params.Region, "region", "", "Region name")
+	cmd.Flags().IntVar(
+		&params.MemorySize, "memory", 512, "Memory size in MB of invoke headers")
+	cmd.Flags().IntVar(
+		&params.Timeout, "timeout", 30, "Function timeout in seconds")
+	return &cmd
+}
+
+func _MakeInvokeCommand() *cobra.Command {
+	var params InvokeParams
+	cmd := cobra.Command{
+		Use:   "invoke",
+		Short: "Invoke deployed function and print response",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoInvoke(params)
+		},
+	}
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVar(
+		&params.Provider, "provider", PROVIDER_TENCENT, "Platform of function: tencent/aliyun")
+	cmd.Flags().StringVar(
+		&params.Region, "region", "", "Region name [required]")
+	cmd.MarkFlagRequired("region")
+	cmd.Flags().StringVar(
+		&params.FunctionName, "function", "", "Function name [required]")
+	cmd.MarkFlagRequired("function")
+	cmd.Flags().StringVar(
+		&params.Qualifier, "qualifier", "", "Function version or alias, default latest")
+	cmd.Flags().StringVar(
+		&params.PayloadFile, "payload-file", "", "Event payload JSON file")
+	cmd.Flags().StringVar(
+		&params.Template, "template", "", "Built-in event payload template: timer/apigw/cos")
+	cmd.Flags().StringVar(
+		&params.Mode, "mode", common.INVOKE_MODE_SYNC, "Invoke mode: sync/async")
+	return &cmd
+}
+
+func _MakeLogsCommand() *cobra.Command {
+	var params LogsParams
+	cmd := cobra.Command{
+		Use:   "logs",
+		Short: "Show and follow function logs",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoLogs(params)
+		},
+	}
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVar(
+		&params.Provider, "provider", PROVIDER_TENCENT, "Platform of function: tencent/aliyun")
+	cmd.Flags().StringVar(
+		&params.Region, "region", "", "Region name [required]")
+	cmd.MarkFlagRequired("region")
+	cmd.Flags().StringVar(
+		&params.FunctionName, "function", "", "Function name [required]")
+	cmd.MarkFlagRequired("function")
+	cmd.Flags().StringVar(
+		&params.Qualifier, "qualifier", "", "Function version or alias, default all")
+	cmd.Flags().DurationVar(
+		&params.Since, "since", 10*time.Minute, "Show logs since duration ago")
+	cmd.Flags().BoolVar(
+		&params.Follow, "follow", false, "Keep polling new logs")
+	cmd.Flags().StringVar(
+		&params.Filter, "filter", "", "Show only logs match the regexp")
+	cmd.Flags().BoolVar(
+		&params.SinceModified, "since-modified", false, "Show only logs since the function last modified, logs of old instances may be included")
+	return &cmd
+}
+
+func _MakeJobCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "job",
+		Short: "Run job function of tencent",
+	}
+	var runParams JobRunParams
+	runCmd := cobra.Command{
+		Use:   "run",
+		Short: "Run job and wait for completion, exit non-zero if failed",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoJobRun(runParams)
+		},
+	}
+	runCmd.Flags().SortFlags = false
+	runCmd.Flags().StringVar(
+		&runParams.Region, "region", "", "Region name [required]")
+	runCmd.MarkFlagRequired("region")
+	runCmd.Flags().StringVar(
+		&runParams.FunctionName, "function", "", "Function name [required]")
+	runCmd.MarkFlagRequired("function")
+	runCmd.Flags().StringVar(
+		&runParams.Qualifier, "qualifier", "", "Function version or alias, default latest")
+	runCmd.Flags().StringArrayVar(
+		&runParams.ArgList, "args", []string{}, `Job argument, sent as payload {"args": [...]}`)
+	runCmd.Flags().DurationVar(
+		&runParams.Timeout, "timeout", 0, "Timeout to wait job finish, default function timeout")
+	cmd.AddCommand(&runCmd)
+	return &cmd
+}
+
+func _MakeConfigCustomDomainAliyunCommand() *cobra.Command {
+	var params AliyunCustomDomainParams
+	cmd := cobra.Command{
+		Use:   "config-custom-domain-aliyun",
+		Short: "Config custom domains and routes of aliyun function compute",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoConfigCustomDomainAliyun(params)
+		},
+	}
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVar(
+		&params.Region, "region", "", "Region name [required]")
+	cmd.MarkFlagRequired("region")
+	cmd.Flags().StringVar(
+		&params.ConfigFile, "config", "", "Custom domains TOML file [required]")
+	cmd.MarkFlagRequired("config")
+	cmd.Flags().BoolVar(
+		&params.Yes, "yes", false, "Confirm update")
 	return &cmd
 }
 
@@ -71,6 +295,21 @@ func _MakeDeployTencentCommand() *cobra.Command {
 	cmd.MarkFlagRequired("region")
 	cmd.Flags().BoolVar(
 		&params.IsJob, "is-job", false, "Is Job Function")
+	cmd.Flags().StringVar(
+		&params.ImageType, "image-type", "personal", "TCR edition of image: personal/enterprise")
+	cmd.Flags().StringVar(
+		&params.RegistryId, "registry-id", "", "TCR enterprise instance id, required for enterprise")
+	cmd.Flags().StringVar(
+		&params.ImageAccelerate, "image-accelerate", "", "ON/OFF image acceleration, default unchanged")
+	_AddTriggersFlags(&cmd, &params.TriggersFile, &params.PruneTriggers)
+	cmd.Flags().StringArrayVar(
+		&params.CDNPurgeDomainList, "cdn-purge-domain", []string{}, "Purge tencent CDN domain after deploy, not supported by deploy-aliyun")
+	cmd.Flags().StringSliceVar(
+		&params.CDNPurge.Paths, "cdn-purge-paths", []string{"/"}, "CDN urls path to purge after deploy")
+	cmd.Flags().StringSliceVar(
+		&params.CDNPurge.Dirs, "cdn-purge-dirs", []string{}, "CDN directories to purge after deploy")
+	cmd.Flags().DurationVar(
+		&params.CDNPurge.Timeout, "cdn-purge-timeout", 10*time.Minute, "Timeout to wait CDN purge")
 	return &cmd
 }
 
@@ -91,11 +330,28 @@ func _MakeBuildCommand() *cobra.Command {
 	return &cmd
 }
 
+func _AddCDNUsageLimitFlags(cmd *cobra.Command, limit *common.CDNUsageLimit) {
+	cmd.Flags().Int64Var(
+		&limit.IpQps, "usagelimit-ip-qps", 0, "Max QPS per IP, default 50")
+	cmd.Flags().Int64Var(
+		&limit.DownstreamKBps, "usagelimit-downstream-kbps", 0, "Downstream capping KBps, default 1024")
+	cmd.Flags().Uint64Var(
+		&limit.BandwidthMbps, "usagelimit-bandwidth-mbps", 0, "Bandwidth alert threshold Mbps, default 10")
+	cmd.Flags().StringVar(
+		&limit.CounterMeasure, "usagelimit-counter-measure", "", "RETURN_404/RESOLVE_DNS_TO_ORIGIN, default RETURN_404")
+	cmd.Flags().Uint64Var(
+		&limit.Cycle, "usagelimit-cycle", 0, "Bandwidth statistic cycle minutes, default 5")
+	cmd.Flags().Uint64Var(
+		&limit.UnblockTime, "usagelimit-unblock-time", 0, "Auto unblock minutes, default 60")
+	cmd.Flags().Uint64Var(
+		&limit.AlertPercentage, "usagelimit-alert-percentage", 0, "Alert percentage of threshold, default 50")
+}
+
 func _MakeConfigCdnCacheTencentCommand() *cobra.Command {
 	var params TencentCDNCacheConfigParams
 	cmd := cobra.Command{
 		Use:   "config-cdn-cache-tencent",
-		Short: "Config CDN cache rules of tencent",
+		Short: "Config CDN cache, access control and header rules of tencent",
 		Run: func(cmd *cobra.Command, args []string) {
 			DoConfigCdnCacheTencent(params)
 		},
@@ -109,6 +365,212 @@ func _MakeConfigCdnCacheTencentCommand() *cobra.Command {
 	cmd.MarkFlagRequired("domain")
 	cmd.Flags().StringVar(
 		&params.UsageLimit, "usagelimit", "", "ON/OFF usage limit")
+	cmd.Flags().StringVar(
+		&params.RulesFile, "rules", "", "CDN rules TOML/JSON file, default cache rules for SPA")
+	_AddCDNUsageLimitFlags(&cmd, &params.UsageLimitOverride)
+	cmd.Flags().StringVar(
+		&params.Export, "export", "", "Export current config to rules TOML/JSON file, without update")
+	cmd.Flags().BoolVar(
+		&params.Yes, "yes", false, "Confirm update")
+	cmd.Flags().BoolVar(
+		&params.Wait, "wait", false, "Wait until config deployed and domain online")
+	cmd.Flags().DurationVar(
+		&params.WaitTimeout, "wait-timeout", 15*time.Minute, "Timeout to wait domain online")
+	return &cmd
+}
+
+func _AddEnvCryptFlags(cmd *cobra.Command, params *EnvCryptParams) {
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVar(
+		&params.Envfile, "envfile", "", "Envfile path [required]")
+	cmd.MarkFlagRequired("envfile")
+	cmd.Flags().StringVar(
+		&params.KeyFile, "key-file", "", "Secret key file, default $EZFAAS_ENV_KEY or ~/.config/ezfaas_env.key")
+}
+
+func _MakeEnvCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "env",
+		Short: "Manage envfiles",
+	}
+	var keygenParams EnvKeygenParams
+	keygenCmd := cobra.Command{
+		Use:   "keygen",
+		Short: "Generate secret key to encrypt envfiles",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoEnvKeygen(keygenParams)
+		},
+	}
+	keygenCmd.Flags().StringVar(
+		&keygenParams.KeyFile, "key-file", "", "Secret key file, default ~/.config/ezfaas_env.key")
+	var encryptParams EnvCryptParams
+	encryptCmd := cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt envfile values in place",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoEnvEncrypt(encryptParams)
+		},
+	}
+	_AddEnvCryptFlags(&encryptCmd, &encryptParams)
+	encryptCmd.Flags().StringVar(
+		&encryptParams.Recipient, "recipient", "", "Recipient public key")
+	var decryptParams EnvCryptParams
+	decryptCmd := cobra.Command{
+		Use:   "decrypt",
+		Short: "Decrypt envfile and print it",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoEnvDecrypt(decryptParams)
+		},
+	}
+	_AddEnvCryptFlags(&decryptCmd, &decryptParams)
+	var editParams EnvCryptParams
+	editCmd := cobra.Command{
+		Use:   "edit",
+		Short: "Edit encrypted envfile with $EDITOR",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoEnvEdit(editParams)
+		},
+	}
+	_AddEnvCryptFlags(&editCmd, &editParams)
+	editCmd.Flags().StringVar(
+		&editParams.Recipient, "recipient", "", "Recipient public key")
+	var checkParams EnvCheckParams
+	checkCmd := cobra.Command{
+		Use:   "check",
+		Short: "Check envfiles against env schema",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoEnvCheck(checkParams)
+		},
+	}
+	checkCmd.Flags().SortFlags = false
+	checkCmd.Flags().StringArrayVar(
+		&checkParams.EnvfileList, "envfile", []string{}, "Envfile path, merged in order [required]")
+	checkCmd.MarkFlagRequired("envfile")
+	checkCmd.Flags().StringVar(
+		&checkParams.Schema, "schema", "", "Env schema file, default env.schema.toml")
+	checkCmd.Flags().StringVar(
+		&checkParams.KeyFile, "key-file", "", "Secret key file to decrypt envfile")
+	checkCmd.Flags().StringVar(
+		&checkParams.Provider, "provider", "", "Platform to resolve secret references: tencent/aliyun")
+	checkCmd.Flags().StringVar(
+		&checkParams.Region, "region", "", "Region to resolve secret references")
+	var diffParams EnvDiffParams
+	diffCmd := cobra.Command{
+		Use:   "diff",
+		Short: "Show variable names diff between two envfiles",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoEnvDiff(diffParams)
+		},
+	}
+	diffCmd.Flags().SortFlags = false
+	diffCmd.Flags().StringVar(
+		&diffParams.From, "from", "", "Old envfile path [required]")
+	diffCmd.MarkFlagRequired("from")
+	diffCmd.Flags().StringVar(
+		&diffParams.To, "to", "", "New envfile path [required]")
+	diffCmd.MarkFlagRequired("to")
+	diffCmd.Flags().StringVar(
+		&diffParams.KeyFile, "key-file", "", "Secret key file to decrypt envfile")
+	cmd.AddCommand(&keygenCmd)
+	cmd.AddCommand(&encryptCmd)
+	cmd.AddCommand(&decryptCmd)
+	cmd.AddCommand(&editCmd)
+	cmd.AddCommand(&checkCmd)
+	cmd.AddCommand(&diffCmd)
+	return &cmd
+}
+
+func _MakeCdnCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "cdn",
+		Short: "Manage CDN",
+	}
+	var configParams CDNConfigParams
+	configCmd := cobra.Command{
+		Use:   "config",
+		Short: "Config CDN cache, access control and header rules",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoCdnConfig(configParams)
+		},
+	}
+	configCmd.Flags().SortFlags = false
+	configCmd.Flags().StringVar(
+		&configParams.Provider, "provider", PROVIDER_TENCENT, "CDN provider: tencent/aliyun")
+	configCmd.Flags().StringVar(
+		&configParams.Region, "region", "", "Region name of tencent")
+	configCmd.Flags().StringVar(
+		&configParams.Domain, "domain", "", "Domain name [required]")
+	configCmd.MarkFlagRequired("domain")
+	configCmd.Flags().StringVar(
+		&configParams.UsageLimit, "usagelimit", "", "ON/OFF usage limit")
+	configCmd.Flags().StringVar(
+		&configParams.RulesFile, "rules", "", "CDN rules TOML/JSON file, default cache rules for SPA")
+	_AddCDNUsageLimitFlags(&configCmd, &configParams.UsageLimitOverride)
+	configCmd.Flags().StringVar(
+		&configParams.Export, "export", "", "Export current config to rules TOML/JSON file, without update")
+	configCmd.Flags().BoolVar(
+		&configParams.Yes, "yes", false, "Confirm update")
+	configCmd.Flags().BoolVar(
+		&configParams.Wait, "wait", false, "Wait until config deployed and domain online")
+	configCmd.Flags().DurationVar(
+		&configParams.WaitTimeout, "wait-timeout", 15*time.Minute, "Timeout to wait domain online")
+	var purgeParams CDNPurgeParams
+	purgeCmd := cobra.Command{
+		Use:   "purge",
+		Short: "Purge tencent CDN cache of urls and directories",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoCdnPurge(purgeParams)
+		},
+	}
+	purgeCmd.Flags().SortFlags = false
+	purgeCmd.Flags().StringVar(
+		&purgeParams.Domain, "domain", "", "Domain name [required]")
+	purgeCmd.MarkFlagRequired("domain")
+	purgeCmd.Flags().StringSliceVar(
+		&purgeParams.Paths, "paths", []string{}, "Url paths to purge, eg: /,/index.html")
+	purgeCmd.Flags().StringSliceVar(
+		&purgeParams.Dirs, "dirs", []string{}, "Directories to purge, eg: /static/")
+	purgeCmd.Flags().StringVar(
+		&purgeParams.FlushType, "flush-type", "flush", "Directory purge type: flush/delete")
+	purgeCmd.Flags().DurationVar(
+		&purgeParams.Timeout, "timeout", 10*time.Minute, "Timeout to wait tasks finish")
+	var prefetchParams CDNPrefetchParams
+	prefetchCmd := cobra.Command{
+		Use:   "prefetch",
+		Short: "Prefetch urls to tencent CDN cache",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoCdnPrefetch(prefetchParams)
+		},
+	}
+	prefetchCmd.Flags().SortFlags = false
+	prefetchCmd.Flags().StringSliceVar(
+		&prefetchParams.Urls, "urls", []string{}, "Urls to prefetch")
+	prefetchCmd.Flags().StringVar(
+		&prefetchParams.UrlsFile, "urls-file", "", "File of urls to prefetch, one url per line")
+	prefetchCmd.Flags().DurationVar(
+		&prefetchParams.Timeout, "timeout", 10*time.Minute, "Timeout to wait tasks finish")
+	var certParams CDNCertParams
+	certCmd := cobra.Command{
+		Use:   "cert",
+		Short: "Upload HTTPS certificate and bind to CDN domains",
+		Run: func(cmd *cobra.Command, args []string) {
+			DoCdnCert(certParams)
+		},
+	}
+	certCmd.Flags().SortFlags = false
+	certCmd.Flags().StringSliceVar(
+		&certParams.Domains, "domain", []string{}, "Domain names, can be repeated [required]")
+	certCmd.MarkFlagRequired("domain")
+	certCmd.Flags().StringVar(
+		&certParams.CertFile, "cert", "", "Certificate PEM file, eg: fullchain.pem [required]")
+	certCmd.MarkFlagRequired("cert")
+	certCmd.Flags().StringVar(
+		&certParams.KeyFile, "key", "", "Private key PEM file, eg: privkey.pem [required]")
+	certCmd.MarkFlagRequired("key")
+	cmd.AddCommand(&configCmd)
+	cmd.AddCommand(&purgeCmd)
+	cmd.AddCommand(&prefetchCmd)
+	cmd.AddCommand(&certCmd)
 	return &cmd
 }
 
@@ -126,7 +588,15 @@ func Main() {
 	cli.AddCommand(_MakeDeployAliyunCommand())
 	cli.AddCommand(_MakeDeployTencentCommand())
 	cli.AddCommand(_MakeBuildCommand())
+	cli.AddCommand(_MakeRunCommand())
+	cli.AddCommand(_MakeServeLocalCommand())
+	cli.AddCommand(_MakeInvokeCommand())
+	cli.AddCommand(_MakeLogsCommand())
+	cli.AddCommand(_MakeJobCommand())
 	cli.AddCommand(_MakeConfigCdnCacheTencentCommand())
+	cli.AddCommand(_MakeConfigCustomDomainAliyunCommand())
+	cli.AddCommand(_MakeEnvCommand())
+	cli.AddCommand(_MakeCdnCommand())
 	err := cli.Execute()
 	if err != nil {
 		os.Exit(1)
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/guyskk/ezfaas/internal/aliyun"
+	"github.com/guyskk/ezfaas/internal/common"
+	"github.com/guyskk/ezfaas/internal/tencent"
+)
+
+type RunParams struct {
+	BaseBuildParams
+	Provider     string
+	FunctionName string
+	Region       string
+	Repository   string
+	BuildId      string
+	EnvfileList  []string
+	EnvKeyFile   string
+	EnvSchema    string
+	EnvList      []string
+	MemorySize   int
+	Timeout      int
+	Port         int  // 容器监听端口，0表示平台默认端口
+	HostPort     int  // 本机端口，0表示与容器端口相同
+	IsEvent      bool // 事件函数，通过本地调用端点转发事件
+	InvokePort   int
+}
+
+type ServeLocalParams struct {
+	Provider     string
+	FunctionName string
+	Region       string
+	MemorySize   int
+	Timeout      int
+	Port         int    // 本地网关监听端口
+	ContainerUrl string // 本地运行的函数容器地址
+}
+
+func _getLocalRuntime(
+	provider string,
+	config common.LocalRunConfig,
+) (common.LocalRuntime, common.EnvRules) {
+	switch provider {
+	case PROVIDER_TENCENT:
+		return tencent.GetLocalRuntime(config), tencent.EnvRules
+	case PROVIDER_ALIYUN:
+		return aliyun.GetLocalRuntime(config), aliyun.EnvRules
+	}
+	log.Fatalf("invalid provider %q, expect %s or %s",
+		provider, PROVIDER_TENCENT, PROVIDER_ALIYUN)
+	return common.LocalRuntime{}, common.EnvRules{}
+}
+
+func _prepareLocalEnv(
+	params RunParams,
+	buildInfo BuildInfo,
+	runtime common.LocalRuntime,
+	rules common.EnvRules,
+) map[string]string {
+	update := _prepareEnvUpdate(BaseDeployParams{
+		EnvfileList: params.EnvfileList,
+		EnvKeyFile:  params.EnvKeyFile,
+		EnvSchema:   params.EnvSchema,
+		EnvList:     params.EnvList,
+	}, buildInfo, _makeProviderSecretResolvers(params.Provider, params.Region), rules)
+	env := map[string]string{}
+	if update != nil {
+		var err error
+		env, _, err = common.ResolveEnv(*update, map[string]string{})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// 平台保留的变量前缀已在校验中排除，不会覆盖用户变量
+	for k, v := range runtime.Env {
+		env[k] = v
+	}
+	return env
+}
+
+func DoRun(params RunParams) {
+	runtime, rules := _getLocalRuntime(params.Provider, common.LocalRunConfig{
+		FunctionName: params.FunctionName,
+		Region:       params.Region,
+		MemorySize:   params.MemorySize,
+		Timeout:      params.Timeout,
+		Port:         params.Port,
+	})
+	var buildInfo BuildInfo
+	if params.BuildId != "" {
+		buildInfo = GetExistedBuildInfo(params.BuildId)
+	} else {
+		buildInfo = NewBuildInfo()
+	}
+	env := _prepareLocalEnv(params, buildInfo, runtime, rules)
+	image := fmt.Sprintf("%s:%s", params.Repository, buildInfo.BuildId)
+	if params.BuildId == "" {
+		buildResult, err := Build(BuildParams{
+			BaseBuildParams: params.BaseBuildParams,
+			Repository:      params.Repository,
+			BuildInfo:       &buildInfo,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		image = buildResult.Image
+	}
+	hostPort := params.HostPort
+	if hostPort <= 0 {
+		hostPort = runtime.ContainerPort
+	}
+	port := fmt.Sprintf("127.0.0.1:%d:%d", hostPort, runtime.ContainerPort)
+	log.Printf("[INFO] Run %s Provider=%s Port=%s Memory=%dMB",
+		image, params.Provider, port, params.MemorySize)
+	if params.IsEvent {
+		go func() {
+			err := common.ServeLocalInvoke(
+				fmt.Sprintf("127.0.0.1:%d", params.InvokePort),
+				fmt.Sprintf("http://127.0.0.1:%d", hostPort),
+				runtime,
+				time.Duration(params.Timeout)*time.Second,
+			)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	err := common.DockerRun(common.DockerRunParams{
+		Image:    image,
+		Name:     fmt.Sprintf("ezfaas-run-%s", params.FunctionName),
+		Platform: params.BuildPlatform,
+		Env:      env,
+		Ports:    []string{port},
+		MemoryMB: params.MemorySize,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func DoServeLocal(params ServeLocalParams) {
+	runtime, _ := _getLocalRuntime(params.Provider, common.LocalRunConfig{
+		FunctionName: params.FunctionName,
+		Region:       params.Region,
+		MemorySize:   params.MemorySize,
+		Timeout:      params.Timeout,
+	})
+	err := common.ServeLocalGateway(
+		fmt.Sprintf("127.0.0.1:%d", params.Port),
+		strings.TrimSuffix(params.ContainerUrl, "/"),
+		runtime,
+		time.Duration(params.Timeout)*time.Second,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
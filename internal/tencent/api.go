@@ -0,0 +1,38 @@
+package tencent
+
+import (
+	"encoding/json"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+)
+
+/* Call tencent cloud API without product SDK, result is the JSON response */
+func _callApi(
+	region string,
+	service string,
+	version string,
+	action string,
+	params map[string]interface{},
+	result interface{},
+) error {
+	provider := common.DefaultProfileProvider()
+	credentail, err := provider.GetCredential()
+	if err != nil {
+		return err
+	}
+	clientProfile := profile.NewClientProfile()
+	client := common.NewCommonClient(credentail, region, clientProfile)
+	request := tchttp.NewCommonRequest(service, version, action)
+	err = request.SetActionParameters(params)
+	if err != nil {
+		return err
+	}
+	response := tchttp.NewCommonResponse()
+	err = client.Send(request, response)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(response.GetBody(), result)
+}
@@ -1,17 +1,25 @@
 package tencent
 
 import (
+	"log"
 	"strings"
+	"time"
 
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
 	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 )
 
 type CDNCacheConfigParams struct {
 	Region     string
 	Domain     string
 	UsageLimit string
+	RulesFile  string // 为空表示使用默认规则
+	// 覆盖规则文件中的用量封顶配置，零值表示不覆盖
+	UsageLimitOverride ezcommon.CDNUsageLimit
+	Export             string // 导出当前配置为规则文件，不做修改
+	Yes                bool
+	Wait               bool // 等待配置部署完成，域名状态恢复online
+	WaitTimeout        time.Duration
 }
 
 var (
@@ -31,151 +39,72 @@ func uint64Ref(x uint64) *uint64 {
 	return &x
 }
 
-func getNodeCacheRules() []*cdn.RuleCache {
-	indexCacheConfig := cdn.RuleCacheConfig{
-		Cache: &cdn.CacheConfigCache{
-			Switch:             &ON,
-			CacheTime:          int64Ref(10),
-			CompareMaxAge:      &OFF,
-			IgnoreCacheControl: &OFF,
-			IgnoreSetCookie:    &OFF,
-		},
-	}
-	staticCacheConfig := cdn.RuleCacheConfig{
-		Cache: &cdn.CacheConfigCache{
-			Switch:             &ON,
-			CacheTime:          int64Ref(10 * 60 * 60),
-			CompareMaxAge:      &OFF,
-			IgnoreCacheControl: &OFF,
-			IgnoreSetCookie:    &OFF,
-		},
-	}
-	apiCacheConfig := cdn.RuleCacheConfig{
-		NoCache: &cdn.CacheConfigNoCache{
-			Switch: &ON,
-		},
-	}
-	return []*cdn.RuleCache{
-		{
-			RuleType: strRef("all"),
-			RulePaths: []*string{
-				strRef("*"),
-			},
-			CacheConfig: &indexCacheConfig,
-		},
-		{
-			RuleType: strRef("directory"),
-			RulePaths: []*string{
-				strRef("/api"),
-			},
-			CacheConfig: &apiCacheConfig,
-		},
-		{
-			RuleType: strRef("directory"),
-			RulePaths: []*string{
-				strRef("/js"),
-				strRef("/css"),
-				strRef("/fonts"),
-				strRef("/imgs"),
-				strRef("/img"),
-				strRef("/libs"),
-				strRef("/static"),
-				strRef("/assets"),
-			},
-			CacheConfig: &staticCacheConfig,
-		},
-		{
-			RuleType: strRef("path"),
-			RulePaths: []*string{
-				strRef("/favicon.ico"),
-			},
-			CacheConfig: &staticCacheConfig,
-		},
-		{
-			RuleType: strRef("path"),
-			RulePaths: []*string{
-				strRef("/manifest.json"),
-				strRef("/service-worker.js"),
-			},
-			CacheConfig: &indexCacheConfig,
-		},
-		{
-			RuleType: strRef("index"),
-			RulePaths: []*string{
-				strRef("/"),
-			},
-			CacheConfig: &indexCacheConfig,
-		},
+func _strRefList(items []string) []*string {
+	var refs []*string
+	for _, item := range items {
+		refs = append(refs, strRef(item))
 	}
+	return refs
 }
 
-func getBrowserCacheRules() []*cdn.MaxAgeRule {
-	indexMaxAgeTime := int64Ref(30)
-	staticMaxAgeTime := int64Ref(10 * 24 * 60 * 60)
-	return []*cdn.MaxAgeRule{
-		{
-			MaxAgeType: strRef("all"),
-			MaxAgeContents: []*string{
-				strRef("*"),
-			},
-			MaxAgeTime:   indexMaxAgeTime,
-			FollowOrigin: &ON,
-		},
-		{
-			MaxAgeType: strRef("directory"),
-			MaxAgeContents: []*string{
-				strRef("/api"),
-			},
-			MaxAgeTime:   int64Ref(0),
-			FollowOrigin: &ON,
-		},
-		{
-			MaxAgeType: strRef("directory"),
-			MaxAgeContents: []*string{
-				strRef("/js"),
-				strRef("/css"),
-				strRef("/fonts"),
-				strRef("/imgs"),
-				strRef("/img"),
-				strRef("/libs"),
-				strRef("/static"),
-				strRef("/assets"),
-			},
-			MaxAgeTime: staticMaxAgeTime,
-		},
-		{
-			MaxAgeType: strRef("path"),
-			MaxAgeContents: []*string{
-				strRef("/favicon.ico"),
-			},
-			MaxAgeTime: staticMaxAgeTime,
-		},
-		{
-			MaxAgeType: strRef("path"),
-			MaxAgeContents: []*string{
-				strRef("/manifest.json"),
-				strRef("/service-worker.js"),
-			},
-			MaxAgeTime: indexMaxAgeTime,
-		},
-		{
-			MaxAgeType: strRef("index"),
-			MaxAgeContents: []*string{
-				strRef("/"),
-			},
-			MaxAgeTime: indexMaxAgeTime,
-		},
-	}
+func getNodeCacheRules(rules []ezcommon.CDNCacheRule) []*cdn.RuleCache {
+	var ruleCacheList []*cdn.RuleCache
+	for _, rule := range rules {
+		var cacheConfig cdn.RuleCacheConfig
+		if rule.NoCache {
+			cacheConfig.NoCache = &cdn.CacheConfigNoCache{
+				Switch: &ON,
+			}
+		} else if rule.FollowOrigin {
+			cacheConfig.FollowOrigin = &cdn.CacheConfigFollowOrigin{
+				Switch: &ON,
+			}
+		} else {
+			cacheConfig.Cache = &cdn.CacheConfigCache{
+				Switch:             &ON,
+				CacheTime:          int64Ref(rule.TTL),
+				CompareMaxAge:      &OFF,
+				IgnoreCacheControl: &OFF,
+				IgnoreSetCookie:    &OFF,
+			}
+		}
+		ruleCacheList = append(ruleCacheList, &cdn.RuleCache{
+			RuleType:    strRef(rule.Type),
+			RulePaths:   _strRefList(rule.Paths),
+			CacheConfig: &cacheConfig,
+		})
+	}
+	return ruleCacheList
+}
+
+func getBrowserCacheRules(rules []ezcommon.CDNMaxAgeRule) []*cdn.MaxAgeRule {
+	var maxAgeRuleList []*cdn.MaxAgeRule
+	for _, rule := range rules {
+		maxAgeRule := cdn.MaxAgeRule{
+			MaxAgeType:     strRef(rule.Type),
+			MaxAgeContents: _strRefList(rule.Paths),
+			MaxAgeTime:     int64Ref(rule.TTL),
+		}
+		if rule.FollowOrigin {
+			maxAgeRule.FollowOrigin = &ON
+		}
+		maxAgeRuleList = append(maxAgeRuleList, &maxAgeRule)
+	}
+	return maxAgeRuleList
 }
 
-func addUsageLimitRule(request *cdn.UpdateDomainConfigRequest, isEnable bool) {
+func addUsageLimitRule(
+	request *cdn.UpdateDomainConfigRequest,
+	isEnable bool,
+	limit ezcommon.CDNUsageLimit,
+) {
 	status := OFF
 	if isEnable {
 		status = ON
 	}
 	request.IpFreqLimit = &cdn.IpFreqLimit{
 		Switch: &status,
-		Qps:    int64Ref(50),
+		Qps:    int64Ref(limit.IpQps),
 	}
 	request.DownstreamCapping = &cdn.DownstreamCapping{
 		Switch: &status,
@@ -185,7 +114,7 @@ func addUsageLimitRule(request *cdn.UpdateDomainConfigRequest, isEnable bool) {
 				RulePaths: []*string{
 					strRef("*"),
 				},
-				KBpsThreshold: int64Ref(1024),
+				KBpsThreshold: int64Ref(limit.DownstreamKBps),
 			},
 		},
 	}
@@ -194,49 +123,115 @@ func addUsageLimitRule(request *cdn.UpdateDomainConfigRequest, isEnable bool) {
 		AlertSwitch:     &ON,
 		Type:            strRef("moment"),
 		Metric:          strRef("bandwidth"),
-		BpsThreshold:    uint64Ref(10 * 1000 * 1000),
-		CounterMeasure:  strRef("RETURN_404"),
-		Cycle:           uint64Ref(5),
-		UnBlockTime:     uint64Ref(60),
-		AlertPercentage: uint64Ref(50),
+		BpsThreshold:    uint64Ref(limit.BandwidthMbps * 1000 * 1000),
+		CounterMeasure:  strRef(limit.CounterMeasure),
+		Cycle:           uint64Ref(limit.Cycle),
+		UnBlockTime:     uint64Ref(limit.UnblockTime),
+		AlertPercentage: uint64Ref(limit.AlertPercentage),
 	}
 	request.BandwidthAlert = &cdn.BandwidthAlert{
 		StatisticItems: []*cdn.StatisticItem{bandwidthAlertItem},
 	}
 }
 
+func _exportCDNRules(rules ezcommon.CDNRules, filepath string) error {
+	content, err := ezcommon.EncodeCDNRules(rules, filepath)
+	if err != nil {
+		return err
+	}
+	err = ezcommon.WriteUserFile(filepath, []byte(content), 0644)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Export CDN rules=%s", filepath)
+	return nil
+}
+
+/*
+Update CDN cache config, show diff of current config and confirm before update.
+Return nil response if exported or nothing changed.
+*/
 func UpdateCDNCacheConfig(
 	params CDNCacheConfigParams,
 ) (*cdn.UpdateDomainConfigResponse, error) {
-	provider := common.DefaultProfileProvider()
-	credentail, err := provider.GetCredential()
+	client, err := _newCDNClient(params.Region)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := _getDomainConfig(client, params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	current := _getDomainCDNRules(detail)
+	if params.Export != "" {
+		return nil, _exportCDNRules(current, params.Export)
+	}
+	rules, err := ezcommon.LoadCDNRules(params.RulesFile)
 	if err != nil {
 		return nil, err
 	}
-	clientProfile := profile.NewClientProfile()
-	client, err := cdn.NewClient(credentail, params.Region, clientProfile)
+	if params.RulesFile != "" {
+		log.Printf("[INFO] CDN rules=%s", params.RulesFile)
+	}
+	ezcommon.MergeCDNUsageLimit(&rules.UsageLimit, params.UsageLimitOverride)
+	err = ezcommon.ValidateCDNRules(*rules)
 	if err != nil {
 		return nil, err
 	}
+	// --usagelimit参数优先，未指定开关时不修改用量封顶配置
+	usageLimit := strings.ToLower(params.UsageLimit)
+	if usageLimit == ON || usageLimit == OFF {
+		isEnable := usageLimit == ON
+		rules.UsageLimit.Enabled = &isEnable
+	}
 	request := cdn.NewUpdateDomainConfigRequest()
 	request.Domain = &params.Domain
 	request.Cache = &cdn.Cache{
-		RuleCache: getNodeCacheRules(),
+		RuleCache: getNodeCacheRules(rules.Cache),
 	}
 	request.MaxAge = &cdn.MaxAge{
 		Switch:      &ON,
-		MaxAgeRules: getBrowserCacheRules(),
+		MaxAgeRules: getBrowserCacheRules(rules.MaxAge),
 	}
 	// 配置限流和用量封顶
-	usageLimit := strings.ToLower(params.UsageLimit)
-	if usageLimit == ON {
-		addUsageLimitRule(request, true)
-	} else if usageLimit == OFF {
-		addUsageLimitRule(request, false)
+	if rules.UsageLimit.Enabled != nil {
+		addUsageLimitRule(request, *rules.UsageLimit.Enabled, rules.UsageLimit)
+	}
+	err = addAccessRules(request, *rules, detail)
+	if err != nil {
+		return nil, err
+	}
+	// 未管理的配置使用当前配置，不显示差异，这些配置不会发送到接口
+	shownRules := *rules
+	if shownRules.UsageLimit.Enabled == nil {
+		shownRules.UsageLimit = current.UsageLimit
+	}
+	_fillUnmanagedAccessRules(&shownRules, current)
+	diffLines, err := _diffCDNRules(current, shownRules)
+	if err != nil {
+		return nil, err
+	}
+	if !ezcommon.HasDiff(diffLines) {
+		log.Printf("[INFO] CDN config of %s has no changes", params.Domain)
+		return nil, nil
+	}
+	log.Printf("[INFO] CDN config diff of %s:\n%s",
+		params.Domain, strings.Join(diffLines, "\n"))
+	if !params.Yes {
+		if !ezcommon.Comfirm("Confirm Update CDN Config") {
+			return nil, ezcommon.ErrCanceled
+		}
 	}
 	response, err := client.UpdateDomainConfig(request)
 	if err != nil {
 		return nil, err
 	}
+	if params.Wait {
+		log.Printf("[INFO] Wait cdn domain %s online...", params.Domain)
+		err = _waitDomainOnline(client, params.Domain, params.WaitTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return response, nil
 }
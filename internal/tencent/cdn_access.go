@@ -0,0 +1,174 @@
+package tencent
+
+import (
+	"fmt"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
+)
+
+func _switchOf(enabled bool) *string {
+	if enabled {
+		return &ON
+	}
+	return &OFF
+}
+
+func _boolValue(x *bool) bool {
+	return x != nil && *x
+}
+
+/* Add access control and header rules to request, nil rules are not changed */
+func addAccessRules(
+	request *cdn.UpdateDomainConfigRequest,
+	rules ezcommon.CDNRules,
+	detail *cdn.DetailDomain,
+) error {
+	if rules.Referer != nil {
+		referer := &cdn.Referer{Switch: _switchOf(rules.Referer.Enabled)}
+		if rules.Referer.Enabled {
+			referer.RefererRules = []*cdn.RefererRule{{
+				RuleType:    strRef(ezcommon.CDN_RULE_ALL),
+				RulePaths:   []*string{strRef("*")},
+				RefererType: strRef(rules.Referer.Type),
+				Referers:    _strRefList(rules.Referer.Referers),
+				AllowEmpty:  &rules.Referer.AllowEmpty,
+			}}
+		}
+		request.Referer = referer
+	}
+	if rules.IpFilter != nil {
+		ipFilter := &cdn.IpFilter{Switch: _switchOf(rules.IpFilter.Enabled)}
+		if rules.IpFilter.Enabled {
+			ipFilter.FilterType = strRef(rules.IpFilter.Type)
+			ipFilter.Filters = _strRefList(rules.IpFilter.Filters)
+		}
+		request.IpFilter = ipFilter
+	}
+	if rules.ResponseHeader != nil {
+		var headerRules []*cdn.HttpHeaderPathRule
+		for _, rule := range rules.ResponseHeader.Rules {
+			headerRules = append(headerRules, &cdn.HttpHeaderPathRule{
+				HeaderMode:  strRef(rule.Mode),
+				HeaderName:  strRef(rule.Name),
+				HeaderValue: strRef(rule.Value),
+				RuleType:    strRef(rule.Type),
+				RulePaths:   _strRefList(rule.Paths),
+			})
+		}
+		request.ResponseHeader = &cdn.ResponseHeader{
+			Switch:      _switchOf(rules.ResponseHeader.Enabled),
+			HeaderRules: headerRules,
+		}
+	}
+	if rules.Compression != nil {
+		var compressionRules []*cdn.CompressionRule
+		for _, rule := range rules.Compression.Rules {
+			compress := true
+			compressionRule := &cdn.CompressionRule{
+				Compress:   &compress,
+				MinLength:  int64Ref(rule.MinLength),
+				MaxLength:  int64Ref(rule.MaxLength),
+				Algorithms: _strRefList(rule.Algorithms),
+				RuleType:   strRef(rule.Type),
+				RulePaths:  _strRefList(rule.Paths),
+			}
+			if rule.Type == ezcommon.CDN_RULE_FILE {
+				compressionRule.FileExtensions = _strRefList(rule.Paths)
+			}
+			compressionRules = append(compressionRules, compressionRule)
+		}
+		request.Compression = &cdn.Compression{
+			Switch:           _switchOf(rules.Compression.Enabled),
+			CompressionRules: compressionRules,
+		}
+	}
+	if rules.Http2 != nil {
+		// HTTP2依赖HTTPS配置，保留当前证书和其他HTTPS设置
+		https := detail.Https
+		if https == nil || !_isSwitchOn(https.Switch) || https.CertInfo == nil {
+			return fmt.Errorf("http2 requires https of %s, please deploy certificate first",
+				_strValue(detail.Domain))
+		}
+		request.Https = _copyHttps(https)
+		request.Https.Http2 = _switchOf(*rules.Http2)
+	}
+	return nil
+}
+
+/* Convert current access control and header config to rules */
+func _getDomainAccessRules(detail *cdn.DetailDomain, rules *ezcommon.CDNRules) {
+	if detail.Https != nil {
+		http2 := _isSwitchOn(detail.Https.Switch) && _isSwitchOn(detail.Https.Http2)
+		rules.Http2 = &http2
+	}
+	if detail.Referer != nil {
+		referer := &ezcommon.CDNRefererRule{Enabled: _isSwitchOn(detail.Referer.Switch)}
+		if len(detail.Referer.RefererRules) > 0 {
+			rule := detail.Referer.RefererRules[0]
+			referer.Type = _strValue(rule.RefererType)
+			referer.Referers = _strList(rule.Referers)
+			referer.AllowEmpty = _boolValue(rule.AllowEmpty)
+		}
+		rules.Referer = referer
+	}
+	if detail.IpFilter != nil {
+		rules.IpFilter = &ezcommon.CDNIpFilterRule{
+			Enabled: _isSwitchOn(detail.IpFilter.Switch),
+			Type:    _strValue(detail.IpFilter.FilterType),
+			Filters: _strList(detail.IpFilter.Filters),
+		}
+	}
+	if detail.ResponseHeader != nil {
+		responseHeader := &ezcommon.CDNResponseHeader{
+			Enabled: _isSwitchOn(detail.ResponseHeader.Switch),
+		}
+		for _, rule := range detail.ResponseHeader.HeaderRules {
+			responseHeader.Rules = append(responseHeader.Rules, ezcommon.CDNHeaderRule{
+				Type:  _strValue(rule.RuleType),
+				Paths: _strList(rule.RulePaths),
+				Mode:  _strValue(rule.HeaderMode),
+				Name:  _strValue(rule.HeaderName),
+				Value: _strValue(rule.HeaderValue),
+			})
+		}
+		rules.ResponseHeader = responseHeader
+	}
+	if detail.Compression != nil {
+		compression := &ezcommon.CDNCompression{
+			Enabled: _isSwitchOn(detail.Compression.Switch),
+		}
+		for _, rule := range detail.Compression.CompressionRules {
+			paths := _strList(rule.RulePaths)
+			if len(paths) <= 0 {
+				paths = _strList(rule.FileExtensions)
+			}
+			compression.Rules = append(compression.Rules, ezcommon.CDNCompressionRule{
+				Type:       _strValue(rule.RuleType),
+				Paths:      paths,
+				Algorithms: _strList(rule.Algorithms),
+				MinLength:  _int64Value(rule.MinLength),
+				MaxLength:  _int64Value(rule.MaxLength),
+			})
+		}
+		rules.Compression = compression
+	}
+}
+
+func _fillUnmanagedAccessRules(rules *ezcommon.CDNRules, current ezcommon.CDNRules) {
+	if rules.Http2 == nil {
+		rules.Http2 = current.Http2
+	}
+	if rules.Referer == nil {
+		rules.Referer = current.Referer
+	}
+	if rules.IpFilter == nil {
+		rules.IpFilter = current.IpFilter
+	}
+	if rules.ResponseHeader == nil {
+		rules.ResponseHeader = current.ResponseHeader
+	}
+	if rules.Compression == nil {
+		rules.Compression = current.Compression
+	}
+}
@@ -0,0 +1,122 @@
+package tencent
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
+)
+
+type CDNCertParams struct {
+	Region   string
+	Domains  []string
+	CertFile string
+	KeyFile  string
+}
+
+/* Upload certificate to SSL service, return id of existed certificate if repeated */
+func _uploadCertificate(region string, cert *ezcommon.Certificate) (string, error) {
+	var result struct {
+		Response struct {
+			CertificateId string
+			RepeatCertId  string
+		}
+	}
+	alias := fmt.Sprintf("ezfaas-%s-%s",
+		cert.Leaf.Subject.CommonName, cert.Leaf.NotAfter.Format("20060102"))
+	err := _callApi(region, "ssl", "2019-12-05", "UploadCertificate",
+		map[string]interface{}{
+			"CertificatePublicKey":  cert.CertPEM,
+			"CertificatePrivateKey": cert.KeyPEM,
+			"CertificateType":       "SVR",
+			"Alias":                 alias,
+			"Repeatable":            false,
+		}, &result)
+	if err != nil {
+		return "", err
+	}
+	if result.Response.RepeatCertId != "" {
+		return result.Response.RepeatCertId, nil
+	}
+	return result.Response.CertificateId, nil
+}
+
+/*
+Copy current HTTPS config to update part of it, other settings such as HTTP2,
+HSTS, OCSP stapling and TLS versions are kept unchanged.
+*/
+func _copyHttps(current *cdn.Https) *cdn.Https {
+	https := cdn.Https{}
+	if current != nil {
+		https = *current
+	}
+	// 证书内容等字段是查询结果，更新时只通过证书ID引用
+	https.CertInfo = &cdn.ServerCert{}
+	if current != nil && current.CertInfo != nil {
+		https.CertInfo.CertId = current.CertInfo.CertId
+	}
+	return &https
+}
+
+func _bindCDNCertificate(client *cdn.Client, domain string, certId string) error {
+	detail, err := _getDomainConfig(client, domain)
+	if err != nil {
+		return err
+	}
+	request := cdn.NewUpdateDomainConfigRequest()
+	request.Domain = strRef(domain)
+	request.Https = _copyHttps(detail.Https)
+	request.Https.Switch = &ON
+	request.Https.CertInfo.CertId = strRef(certId)
+	_, err = client.UpdateDomainConfig(request)
+	return err
+}
+
+/*
+Upload certificate from PEM files and bind it to HTTPS config of CDN domains.
+All domains are checked before upload, failed domains are reported at end.
+*/
+func DeployCDNCertificate(params CDNCertParams) error {
+	if len(params.Domains) <= 0 {
+		return fmt.Errorf("domains is required")
+	}
+	cert, err := ezcommon.LoadCertificate(params.CertFile, params.KeyFile)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Certificate %s", cert.String())
+	var violations []string
+	for _, domain := range params.Domains {
+		if err := ezcommon.CheckCertificate(cert, domain); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %s", domain, err))
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("invalid certificate:\n  - %s", strings.Join(violations, "\n  - "))
+	}
+	certId, err := _uploadCertificate(params.Region, cert)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Upload certificate CertId=%s", certId)
+	client, err := _newCDNClient(params.Region)
+	if err != nil {
+		return err
+	}
+	var failedDomains []string
+	for _, domain := range params.Domains {
+		err := _bindCDNCertificate(client, domain, certId)
+		if err != nil {
+			log.Printf("[WARN] Bind certificate Domain=%s %s", domain, err)
+			failedDomains = append(failedDomains, domain)
+			continue
+		}
+		log.Printf("[INFO] Bind certificate Domain=%s CertId=%s", domain, certId)
+	}
+	if len(failedDomains) > 0 {
+		return fmt.Errorf("bind certificate failed: %s", strings.Join(failedDomains, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package tencent
+
+import (
+	"testing"
+
+	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
+)
+
+func TestCopyHttps(t *testing.T) {
+	current := &cdn.Https{
+		Switch: strRef(ON),
+		Http2:  strRef(ON),
+		CertInfo: &cdn.ServerCert{
+			CertId:      strRef("old"),
+			Certificate: strRef("-----BEGIN CERTIFICATE-----"),
+		},
+	}
+	https := _copyHttps(current)
+	https.CertInfo.CertId = strRef("new")
+	if _strValue(https.Http2) != ON || _strValue(https.Switch) != ON {
+		t.Errorf("https settings not kept: %v", https)
+	}
+	if https.CertInfo.Certificate != nil {
+		t.Error("certificate content should not be sent")
+	}
+	if _strValue(current.CertInfo.CertId) != "old" {
+		t.Error("current config modified")
+	}
+	https = _copyHttps(nil)
+	if https.CertInfo == nil || https.CertInfo.CertId != nil {
+		t.Errorf("empty https = %v", https)
+	}
+}
@@ -0,0 +1,195 @@
+package tencent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
+)
+
+func _getDomainConfig(client *cdn.Client, domain string) (*cdn.DetailDomain, error) {
+	request := cdn.NewDescribeDomainsConfigRequest()
+	request.Filters = []*cdn.DomainFilter{
+		{
+			Name:  strRef("domain"),
+			Value: []*string{strRef(domain)},
+		},
+	}
+	response, err := client.DescribeDomainsConfig(request)
+	if err != nil {
+		return nil, err
+	}
+	for _, detail := range response.Response.Domains {
+		if detail.Domain != nil && *detail.Domain == domain {
+			return detail, nil
+		}
+	}
+	return nil, fmt.Errorf("cdn domain %s not found", domain)
+}
+
+const (
+	//	已启动
+	CDN_DOMAIN_STATUS_ONLINE string = "online"
+	//	已关闭
+	CDN_DOMAIN_STATUS_OFFLINE string = "offline"
+	//	部署中
+	CDN_DOMAIN_STATUS_PROCESSING string = "processing"
+)
+
+func _getDomainStatus(client *cdn.Client, domain string) (string, error) {
+	request := cdn.NewDescribeDomainsRequest()
+	request.Filters = []*cdn.DomainFilter{
+		{
+			Name:  strRef("domain"),
+			Value: []*string{strRef(domain)},
+		},
+	}
+	response, err := client.DescribeDomains(request)
+	if err != nil {
+		return "", err
+	}
+	for _, brief := range response.Response.Domains {
+		if brief.Domain != nil && *brief.Domain == domain {
+			return _strValue(brief.Status), nil
+		}
+	}
+	return "", fmt.Errorf("cdn domain %s not found", domain)
+}
+
+// 更新后域名状态不会立即变为processing，至少等待该时间才认为已部署
+const _domainProcessingDelay = 30 * time.Second
+
+/*
+Wait domain config deployed, the status is processing until finished.
+The status is still online right after update, so online is accepted only
+after processing is seen or the delay passed.
+*/
+func _waitDomainOnline(
+	client *cdn.Client,
+	domain string,
+	timeout time.Duration,
+) error {
+	start := time.Now()
+	isProcessing := false
+	target := fmt.Sprintf("cdn domain %s online", domain)
+	return _waitStatus(target, 5*time.Second, timeout, func() (string, bool, error) {
+		status, err := _getDomainStatus(client, domain)
+		if err != nil {
+			return "", false, err
+		}
+		switch status {
+		case CDN_DOMAIN_STATUS_PROCESSING:
+			isProcessing = true
+			return status, false, nil
+		case CDN_DOMAIN_STATUS_ONLINE:
+			return status, isProcessing || time.Since(start) >= _domainProcessingDelay, nil
+		}
+		return status, false, fmt.Errorf("cdn domain %s not online, status=%s", domain, status)
+	})
+}
+
+func _strValue(x *string) string {
+	if x == nil {
+		return ""
+	}
+	return *x
+}
+
+func _int64Value(x *int64) int64 {
+	if x == nil {
+		return 0
+	}
+	return *x
+}
+
+func _uint64Value(x *uint64) uint64 {
+	if x == nil {
+		return 0
+	}
+	return *x
+}
+
+func _strList(refs []*string) []string {
+	var items []string
+	for _, ref := range refs {
+		items = append(items, _strValue(ref))
+	}
+	return items
+}
+
+func _isSwitchOn(x *string) bool {
+	return strings.ToLower(_strValue(x)) == ON
+}
+
+/* Convert current domain config to rules, reverse of the update request */
+func _getDomainCDNRules(detail *cdn.DetailDomain) ezcommon.CDNRules {
+	var rules ezcommon.CDNRules
+	if detail.Cache != nil {
+		for _, ruleCache := range detail.Cache.RuleCache {
+			rule := ezcommon.CDNCacheRule{
+				Type:  _strValue(ruleCache.RuleType),
+				Paths: _strList(ruleCache.RulePaths),
+			}
+			config := ruleCache.CacheConfig
+			if config != nil {
+				if config.NoCache != nil && _isSwitchOn(config.NoCache.Switch) {
+					rule.NoCache = true
+				} else if config.FollowOrigin != nil && _isSwitchOn(config.FollowOrigin.Switch) {
+					rule.FollowOrigin = true
+				} else if config.Cache != nil {
+					rule.TTL = _int64Value(config.Cache.CacheTime)
+				}
+			}
+			rules.Cache = append(rules.Cache, rule)
+		}
+	}
+	if detail.MaxAge != nil {
+		for _, maxAgeRule := range detail.MaxAge.MaxAgeRules {
+			rules.MaxAge = append(rules.MaxAge, ezcommon.CDNMaxAgeRule{
+				Type:         _strValue(maxAgeRule.MaxAgeType),
+				Paths:        _strList(maxAgeRule.MaxAgeContents),
+				TTL:          _int64Value(maxAgeRule.MaxAgeTime),
+				FollowOrigin: _isSwitchOn(maxAgeRule.FollowOrigin),
+			})
+		}
+	}
+	limit := &rules.UsageLimit
+	if detail.IpFreqLimit != nil {
+		enabled := _isSwitchOn(detail.IpFreqLimit.Switch)
+		limit.Enabled = &enabled
+		limit.IpQps = _int64Value(detail.IpFreqLimit.Qps)
+	}
+	capping := detail.DownstreamCapping
+	if capping != nil && len(capping.CappingRules) > 0 {
+		limit.DownstreamKBps = _int64Value(capping.CappingRules[0].KBpsThreshold)
+	}
+	alert := detail.BandwidthAlert
+	if alert != nil && len(alert.StatisticItems) > 0 {
+		item := alert.StatisticItems[0]
+		limit.BandwidthMbps = _uint64Value(item.BpsThreshold) / (1000 * 1000)
+		limit.CounterMeasure = _strValue(item.CounterMeasure)
+		limit.Cycle = _uint64Value(item.Cycle)
+		limit.UnblockTime = _uint64Value(item.UnBlockTime)
+		limit.AlertPercentage = _uint64Value(item.AlertPercentage)
+	}
+	_getDomainAccessRules(detail, &rules)
+	return rules
+}
+
+/* Diff current rules and new rules, both encoded as TOML */
+func _diffCDNRules(current ezcommon.CDNRules, rules ezcommon.CDNRules) ([]string, error) {
+	currentText, err := ezcommon.EncodeCDNRules(current, "")
+	if err != nil {
+		return nil, err
+	}
+	rulesText, err := ezcommon.EncodeCDNRules(rules, "")
+	if err != nil {
+		return nil, err
+	}
+	return ezcommon.DiffLines(
+		strings.Split(strings.TrimSpace(currentText), "\n"),
+		strings.Split(strings.TrimSpace(rulesText), "\n"),
+	), nil
+}
@@ -0,0 +1,39 @@
+package tencent
+
+import (
+	"strings"
+	"testing"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+)
+
+func TestDiffCDNRules(t *testing.T) {
+	rootRule := func(ttl int64) ezcommon.CDNRules {
+		return ezcommon.CDNRules{Cache: []ezcommon.CDNCacheRule{{Type: "path", Paths: []string{"/"}, TTL: ttl}}}
+	}
+	cases := []struct {
+		name    string
+		current ezcommon.CDNRules
+		rules   ezcommon.CDNRules
+		changed []string // 变更的行，去除空白
+	}{
+		{"no changes", rootRule(60), rootRule(60), nil},
+		{"changed ttl", rootRule(60), rootRule(120), []string{"-ttl = 60", "+ttl = 120"}},
+	}
+	for _, c := range cases {
+		lines, err := _diffCDNRules(c.current, c.rules)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		var changed []string
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "  ") {
+				changed = append(changed, line[:1]+strings.TrimSpace(line[1:]))
+			}
+		}
+		if strings.Join(changed, "\n") != strings.Join(c.changed, "\n") {
+			t.Errorf("%s: changed = %q, expect %q", c.name, changed, c.changed)
+		}
+	}
+}
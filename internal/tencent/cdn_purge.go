@@ -0,0 +1,242 @@
+package tencent
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+)
+
+const (
+	CDN_TASK_PURGE_URL  string = "purge-url"
+	CDN_TASK_PURGE_PATH string = "purge-path"
+	CDN_TASK_PREFETCH   string = "prefetch"
+)
+
+const (
+	//	任务处理中
+	CDN_TASK_STATUS_PROCESS string = "process"
+	//	任务完成
+	CDN_TASK_STATUS_DONE string = "done"
+	//	任务失败
+	CDN_TASK_STATUS_FAIL string = "fail"
+	//	任务提交的URL无效
+	CDN_TASK_STATUS_INVALID string = "invalid"
+)
+
+type CDNPurgeParams struct {
+	Region    string
+	Domain    string
+	Paths     []string // 刷新URL，相对域名的路径
+	Dirs      []string // 刷新目录，相对域名的路径
+	FlushType string   // flush: 刷新变更资源，delete: 刷新全部资源
+	Timeout   time.Duration
+}
+
+type CDNPrefetchParams struct {
+	Region  string
+	Urls    []string
+	Timeout time.Duration
+}
+
+type CDNTask struct {
+	TaskId   string
+	TaskType string
+	Status   map[string]string // url -> status
+}
+
+func _newCDNClient(region string) (*cdn.Client, error) {
+	provider := common.DefaultProfileProvider()
+	credentail, err := provider.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	clientProfile := profile.NewClientProfile()
+	return cdn.NewClient(credentail, region, clientProfile)
+}
+
+/* Url scheme of domain, https if HTTPS is enabled */
+func _getDomainScheme(detail *cdn.DetailDomain) string {
+	if detail.Https != nil && _isSwitchOn(detail.Https.Switch) {
+		return "https"
+	}
+	return "http"
+}
+
+func _getDomainUrls(scheme string, domain string, paths []string) []*string {
+	var urls []*string
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		urls = append(urls, strRef(fmt.Sprintf("%s://%s%s", scheme, domain, path)))
+	}
+	return urls
+}
+
+func _describeCDNTask(client *cdn.Client, task *CDNTask) error {
+	status := map[string]string{}
+	if task.TaskType == CDN_TASK_PREFETCH {
+		request := cdn.NewDescribePushTasksRequest()
+		request.TaskId = &task.TaskId
+		response, err := client.DescribePushTasks(request)
+		if err != nil {
+			return err
+		}
+		for _, item := range response.Response.PushLogs {
+			status[*item.Url] = *item.Status
+		}
+	} else {
+		request := cdn.NewDescribePurgeTasksRequest()
+		request.TaskId = &task.TaskId
+		response, err := client.DescribePurgeTasks(request)
+		if err != nil {
+			return err
+		}
+		for _, item := range response.Response.PurgeLogs {
+			status[*item.Url] = *item.Status
+		}
+	}
+	task.Status = status
+	return nil
+}
+
+func _isCDNTaskFinished(task *CDNTask) bool {
+	if len(task.Status) <= 0 {
+		return false
+	}
+	for _, status := range task.Status {
+		if status == CDN_TASK_STATUS_PROCESS {
+			return false
+		}
+	}
+	return true
+}
+
+func _waitCDNTasks(
+	client *cdn.Client,
+	tasks []*CDNTask,
+	timeout time.Duration,
+) error {
+	deadline := time.Now().Add(timeout)
+	i := 1
+	for {
+		isFinished := true
+		for _, task := range tasks {
+			if _isCDNTaskFinished(task) {
+				continue
+			}
+			err := _describeCDNTask(client, task)
+			if err != nil {
+				return err
+			}
+			if !_isCDNTaskFinished(task) {
+				isFinished = false
+			}
+		}
+		if isFinished {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("cdn tasks not finished in %s", timeout)
+		}
+		if i%3 == 0 {
+			log.Printf("[INFO] Wait cdn tasks finish...")
+		}
+		time.Sleep(time.Duration(3 * time.Second))
+		i += 1
+	}
+	var failedUrls []string
+	for _, task := range tasks {
+		for url, status := range task.Status {
+			log.Printf("[INFO] TaskId=%s %s %s", task.TaskId, status, url)
+			if status != CDN_TASK_STATUS_DONE {
+				failedUrls = append(failedUrls, url)
+			}
+		}
+	}
+	if len(failedUrls) > 0 {
+		return fmt.Errorf("cdn tasks failed: %s", strings.Join(failedUrls, ", "))
+	}
+	return nil
+}
+
+/* Purge CDN cache of urls and directories, wait until tasks finished */
+func PurgeCDNCache(params CDNPurgeParams) ([]*CDNTask, error) {
+	if len(params.Paths) <= 0 && len(params.Dirs) <= 0 {
+		return nil, fmt.Errorf("paths or dirs is required")
+	}
+	client, err := _newCDNClient(params.Region)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := _getDomainConfig(client, params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	scheme := _getDomainScheme(detail)
+	var tasks []*CDNTask
+	if len(params.Paths) > 0 {
+		request := cdn.NewPurgeUrlsCacheRequest()
+		request.Urls = _getDomainUrls(scheme, params.Domain, params.Paths)
+		response, err := client.PurgeUrlsCache(request)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[INFO] Purge urls TaskId=%s", *response.Response.TaskId)
+		tasks = append(tasks, &CDNTask{
+			TaskId:   *response.Response.TaskId,
+			TaskType: CDN_TASK_PURGE_URL,
+		})
+	}
+	if len(params.Dirs) > 0 {
+		flushType := params.FlushType
+		if flushType == "" {
+			flushType = "flush"
+		}
+		request := cdn.NewPurgePathCacheRequest()
+		request.Paths = _getDomainUrls(scheme, params.Domain, params.Dirs)
+		request.FlushType = &flushType
+		response, err := client.PurgePathCache(request)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[INFO] Purge dirs TaskId=%s", *response.Response.TaskId)
+		tasks = append(tasks, &CDNTask{
+			TaskId:   *response.Response.TaskId,
+			TaskType: CDN_TASK_PURGE_PATH,
+		})
+	}
+	err = _waitCDNTasks(client, tasks, params.Timeout)
+	return tasks, err
+}
+
+/* Prefetch urls to CDN cache, wait until task finished */
+func PrefetchCDNCache(params CDNPrefetchParams) ([]*CDNTask, error) {
+	if len(params.Urls) <= 0 {
+		return nil, fmt.Errorf("urls is required")
+	}
+	client, err := _newCDNClient(params.Region)
+	if err != nil {
+		return nil, err
+	}
+	request := cdn.NewPushUrlsCacheRequest()
+	for _, url := range params.Urls {
+		request.Urls = append(request.Urls, strRef(url))
+	}
+	response, err := client.PushUrlsCache(request)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Prefetch urls TaskId=%s", *response.Response.TaskId)
+	tasks := []*CDNTask{{
+		TaskId:   *response.Response.TaskId,
+		TaskType: CDN_TASK_PREFETCH,
+	}}
+	err = _waitCDNTasks(client, tasks, params.Timeout)
+	return tasks, err
+}
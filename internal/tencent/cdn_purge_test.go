@@ -0,0 +1,32 @@
+package tencent
+
+import (
+	"reflect"
+	"testing"
+
+	cdn "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cdn/v20180606"
+)
+
+func TestGetDomainUrls(t *testing.T) {
+	cases := []struct {
+		name   string
+		detail *cdn.DetailDomain
+		expect []string
+	}{
+		{"no https", &cdn.DetailDomain{}, []string{"http://example.com/", "http://example.com/static/"}},
+		{"https off", &cdn.DetailDomain{Https: &cdn.Https{Switch: strRef(OFF)}},
+			[]string{"http://example.com/", "http://example.com/static/"}},
+		{"https on", &cdn.DetailDomain{Https: &cdn.Https{Switch: strRef(ON)}},
+			[]string{"https://example.com/", "https://example.com/static/"}},
+	}
+	for _, c := range cases {
+		scheme := _getDomainScheme(c.detail)
+		var urls []string
+		for _, url := range _getDomainUrls(scheme, "example.com", []string{"/", "static/"}) {
+			urls = append(urls, *url)
+		}
+		if !reflect.DeepEqual(urls, c.expect) {
+			t.Errorf("%s: urls = %q, expect %q", c.name, urls, c.expect)
+		}
+	}
+}
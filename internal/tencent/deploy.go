@@ -13,13 +13,22 @@ import (
 )
 
 type DeployParams struct {
-	Region               string
-	FunctionName         string
-	Repository           string
-	BuildId              string
-	ImagePort            *int64 // -1表示Job函数，没有端口
-	EnvironmentVariables *map[string]string
-	Yes                  bool
+	Region          string
+	FunctionName    string
+	Repository      string
+	BuildId         string
+	ImagePort       *int64                   // -1表示Job函数，没有端口
+	Command         string                   // 容器启动命令，为空表示不修改
+	Args            string                   // 容器启动参数，为空表示不修改
+	EntryPoint      string                   // 为空表示不修改
+	ImageType       string                   // personal 或 enterprise，默认personal
+	RegistryId      string                   // 企业版镜像仓库实例ID
+	ImageAccelerate string                   // 镜像加速 on/off，为空表示不修改
+	EnvUpdate       *ezcommon.EnvUpdate      // nil表示不更新环境变量
+	Triggers        *ezcommon.TriggersConfig // nil表示不管理触发器
+	PruneTriggers   bool                     // 删除未声明的非ezfaas管理的触发器
+	SmokeChecks     *ezcommon.SmokeConfig    // nil表示不执行冒烟测试
+	Yes             bool
 }
 
 const (
@@ -72,35 +81,51 @@ func _getFunctionStatus(
 	return *response.Response.Status, nil
 }
 
-func _waitFunctionActive(
-	client *scf.Client,
-	params DeployParams,
+/* Poll status every interval until done, error or timeout */
+func _waitStatus(
+	target string,
+	interval time.Duration,
 	timeout time.Duration,
+	poll func() (status string, isDone bool, err error),
 ) error {
 	deadline := time.Now().Add(timeout)
 	i := 1
 	for {
-		status, err := _getFunctionStatus(client, params)
+		status, isDone, err := poll()
 		if err != nil {
 			return err
 		}
-		if status == FUNCTION_STATUS_ACTIVE {
+		if isDone {
 			return nil
 		}
-		if _isFailedStatus(status) {
-			return fmt.Errorf("function failed, status=%s", status)
-		}
 		if time.Now().After(deadline) {
-			return fmt.Errorf("function not active, status=%s", status)
+			return fmt.Errorf("%s timeout after %s, status=%s", target, timeout, status)
 		}
 		if i%3 == 0 {
-			log.Printf("[INFO] Wait function active, status=%s", status)
+			log.Printf("[INFO] Wait %s, status=%s", target, status)
 		}
-		time.Sleep(time.Duration(1 * time.Second))
+		time.Sleep(interval)
 		i += 1
 	}
 }
 
+func _waitFunctionActive(
+	client *scf.Client,
+	params DeployParams,
+	timeout time.Duration,
+) error {
+	return _waitStatus("function active", 1*time.Second, timeout, func() (string, bool, error) {
+		status, err := _getFunctionStatus(client, params)
+		if err != nil {
+			return "", false, err
+		}
+		if _isFailedStatus(status) {
+			return status, false, fmt.Errorf("function failed, status=%s", status)
+		}
+		return status, status == FUNCTION_STATUS_ACTIVE, nil
+	})
+}
+
 func _updateCode(
 	client *scf.Client,
 	params DeployParams,
@@ -110,31 +135,116 @@ func _updateCode(
 	if err != nil {
 		return nil, err
 	}
-	var containerImageAccelerate *bool = nil
-	imageConfig := functionInfoResponse.Response.ImageConfig
+	newImageConfig := _getImageConfig(functionInfoResponse.Response.ImageConfig, params, imageUri)
+	return _updateImageConfig(client, params, newImageConfig)
+}
+
+/* Image config of new image, unspecified settings are kept the same as current */
+func _getImageConfig(
+	imageConfig *scf.ImageConfig,
+	params DeployParams,
+	imageUri string,
+) *scf.ImageConfig {
+	imageType := params.ImageType
+	if imageType == "" {
+		imageType = IMAGE_TYPE_PERSONAL
+	}
+	newImageConfig := scf.ImageConfig{
+		ImageType: &imageType,
+		ImageUri:  &imageUri,
+	}
+	if imageType == IMAGE_TYPE_ENTERPRISE {
+		newImageConfig.RegistryId = &params.RegistryId
+	}
+	// 未指定的端口、启动命令和参数保持不变
 	if imageConfig != nil {
-		containerImageAccelerate = imageConfig.ContainerImageAccelerate
+		newImageConfig.ImagePort = imageConfig.ImagePort
+		newImageConfig.ContainerImageAccelerate = imageConfig.ContainerImageAccelerate
+		newImageConfig.Command = imageConfig.Command
+		newImageConfig.Args = imageConfig.Args
+		newImageConfig.EntryPoint = imageConfig.EntryPoint
+	}
+	if params.ImagePort != nil {
+		newImageConfig.ImagePort = params.ImagePort
+	}
+	if params.ImageAccelerate != "" {
+		isAccelerate := strings.ToLower(params.ImageAccelerate) == ON
+		newImageConfig.ContainerImageAccelerate = &isAccelerate
 	}
+	if params.Command != "" {
+		newImageConfig.Command = &params.Command
+	}
+	if params.Args != "" {
+		newImageConfig.Args = &params.Args
+	}
+	if params.EntryPoint != "" {
+		newImageConfig.EntryPoint = &params.EntryPoint
+	}
+	return &newImageConfig
+}
+
+func _updateImageConfig(
+	client *scf.Client,
+	params DeployParams,
+	imageConfig *scf.ImageConfig,
+) (*scf.UpdateFunctionCodeResponse, error) {
 	request := scf.NewUpdateFunctionCodeRequest()
 	request.FunctionName = &params.FunctionName
-	imageType := "personal"
-	request.Code = &scf.Code{
-		ImageConfig: &scf.ImageConfig{
-			ImageType:                &imageType,
-			ImageUri:                 &imageUri,
-			ImagePort:                params.ImagePort,
-			ContainerImageAccelerate: containerImageAccelerate,
-		},
-	}
+	request.Code = &scf.Code{ImageConfig: imageConfig}
 	return client.UpdateFunctionCode(request)
 }
 
+/*
+Image config to restore previous exactly, not merged with current config.
+Empty command, args, entrypoint and disabled acceleration are sent
+explicitly to clear new values.
+*/
+func _getRollbackImageConfig(previous *scf.ImageConfig) *scf.ImageConfig {
+	imageConfig := *previous
+	imageConfig.Command = strRef(_strValue(previous.Command))
+	imageConfig.Args = strRef(_strValue(previous.Args))
+	imageConfig.EntryPoint = strRef(_strValue(previous.EntryPoint))
+	isAccelerate := previous.ContainerImageAccelerate != nil && *previous.ContainerImageAccelerate
+	imageConfig.ContainerImageAccelerate = &isAccelerate
+	if imageConfig.ImageType == nil {
+		imageConfig.ImageType = strRef(IMAGE_TYPE_PERSONAL)
+	}
+	return &imageConfig
+}
+
+func _getFunctionEnv(
+	client *scf.Client,
+	params DeployParams,
+) (map[string]string, error) {
+	response, err := _getFunctionInfo(client, params)
+	if err != nil {
+		return nil, err
+	}
+	return _getResponseEnv(response), nil
+}
+
+func _getResponseEnv(response *scf.GetFunctionResponse) map[string]string {
+	env := map[string]string{}
+	environment := response.Response.Environment
+	if environment == nil {
+		return env
+	}
+	for _, variable := range environment.Variables {
+		if variable.Key == nil || variable.Value == nil {
+			continue
+		}
+		env[*variable.Key] = *variable.Value
+	}
+	return env
+}
+
 func _updateConfig(
 	client *scf.Client,
 	params DeployParams,
+	env map[string]string,
 ) (*scf.UpdateFunctionConfigurationResponse, error) {
 	var Variables []*scf.Variable
-	for k, v := range *params.EnvironmentVariables {
+	for k, v := range env {
 		key, value := k, v // 更新变量地址
 		Variables = append(Variables, &scf.Variable{Key: &key, Value: &value})
 	}
@@ -144,18 +254,66 @@ func _updateConfig(
 	return client.UpdateFunctionConfiguration(request)
 }
 
+func _maskSecretEnv(response *scf.GetFunctionResponse, update *ezcommon.EnvUpdate) {
+	environment := response.Response.Environment
+	if environment == nil {
+		return
+	}
+	for _, variable := range environment.Variables {
+		if variable.Key != nil && ezcommon.IsSecretEnv(update, *variable.Key) {
+			variable.Value = strRef(ezcommon.SECRET_MASK)
+		}
+	}
+}
+
+func _validateImageParams(params DeployParams) error {
+	switch params.ImageType {
+	case "", IMAGE_TYPE_PERSONAL:
+	case IMAGE_TYPE_ENTERPRISE:
+		if params.RegistryId == "" {
+			return fmt.Errorf("registry id is required for enterprise image")
+		}
+	default:
+		return fmt.Errorf("invalid image type %q, expect %s or %s",
+			params.ImageType, IMAGE_TYPE_PERSONAL, IMAGE_TYPE_ENTERPRISE)
+	}
+	switch strings.ToLower(params.ImageAccelerate) {
+	case "", ON, OFF:
+	default:
+		return fmt.Errorf("invalid image accelerate %q, expect on or off", params.ImageAccelerate)
+	}
+	return nil
+}
+
 func DoDeploy(params DeployParams) (*scf.GetFunctionResponse, error) {
+	err := _validateImageParams(params)
+	if err != nil {
+		return nil, err
+	}
 	dockerImage := fmt.Sprintf("%s:%s", params.Repository, params.BuildId)
 	imageDigest, digestErr := ezcommon.GetDockerImageDigest(dockerImage)
 	if digestErr != nil {
 		return nil, digestErr
 	}
 	imageUri := fmt.Sprintf("%s@%s", dockerImage, imageDigest)
-	hasEnvironmentVariables := params.EnvironmentVariables != nil
+	hasEnvironmentVariables := params.EnvUpdate != nil
 	log.Printf("[INFO] Region=%s Function=%s", params.Region, params.FunctionName)
 	log.Printf("[INFO] ContainerImage=%s", dockerImage)
 	log.Printf("[INFO] ContainerImageDigest=%s", imageDigest)
 	log.Printf("[INFO] UpdateEnvironmentVariables=%t", hasEnvironmentVariables)
+	if params.ImagePort != nil {
+		log.Printf("[INFO] ImagePort=%d", *params.ImagePort)
+	}
+	if params.ImageType == IMAGE_TYPE_ENTERPRISE {
+		log.Printf("[INFO] ImageType=%s RegistryId=%s", params.ImageType, params.RegistryId)
+	}
+	if params.ImageAccelerate != "" {
+		log.Printf("[INFO] ImageAccelerate=%s", params.ImageAccelerate)
+	}
+	if params.Command != "" || params.Args != "" || params.EntryPoint != "" {
+		log.Printf("[INFO] Command=%q Args=%q EntryPoint=%q",
+			params.Command, params.Args, params.EntryPoint)
+	}
 	provider := common.DefaultProfileProvider()
 	credentail, err := provider.GetCredential()
 	if err != nil {
@@ -166,6 +324,33 @@ func DoDeploy(params DeployParams) (*scf.GetFunctionResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	var env map[string]string
+	if hasEnvironmentVariables {
+		log.Printf("[INFO] EnvMode=%s", params.EnvUpdate.Mode)
+		currentEnv, envErr := _getFunctionEnv(client, params)
+		if envErr != nil {
+			return nil, envErr
+		}
+		var deleted []string
+		env, deleted, envErr = ezcommon.ResolveEnv(*params.EnvUpdate, currentEnv)
+		if envErr != nil {
+			return nil, envErr
+		}
+		ezcommon.LogDeletedEnv(params.EnvUpdate.Mode, deleted)
+		envErr = ezcommon.ValidateEnv(EnvRules, env)
+		if envErr != nil {
+			return nil, envErr
+		}
+	}
+	var triggerPlan *TriggerPlan
+	if params.Triggers != nil {
+		triggerPlan, err = _planTriggers(
+			client, params.FunctionName, params.Triggers, params.PruneTriggers)
+		if err != nil {
+			return nil, err
+		}
+		_logTriggerPlan(triggerPlan)
+	}
 	if !params.Yes {
 		if !ezcommon.ComfirmDeploy() {
 			return nil, ezcommon.ErrCanceled
@@ -182,11 +367,17 @@ func DoDeploy(params DeployParams) (*scf.GetFunctionResponse, error) {
 		Region:     params.Region,
 		Repository: params.Repository,
 		BuildId:    params.BuildId,
+		ImageType:  params.ImageType,
+		RegistryId: params.RegistryId,
 		Timeout:    time.Duration(30 * time.Second),
 	})
 	if imageErr != nil {
 		return nil, imageErr
 	}
+	previous, err := _getFunctionInfo(client, params)
+	if err != nil {
+		return nil, err
+	}
 	log.Println("[INFO] Update function code...")
 	_, codeErr := _updateCode(client, params, imageUri)
 	if codeErr != nil {
@@ -199,7 +390,7 @@ func DoDeploy(params DeployParams) (*scf.GetFunctionResponse, error) {
 	}
 	if hasEnvironmentVariables {
 		log.Println("[INFO] Update function config...")
-		_, configErr := _updateConfig(client, params)
+		_, configErr := _updateConfig(client, params, env)
 		if configErr != nil {
 			return nil, configErr
 		}
@@ -208,9 +399,76 @@ func DoDeploy(params DeployParams) (*scf.GetFunctionResponse, error) {
 			return nil, err
 		}
 	}
+	if triggerPlan != nil && triggerPlan.HasChanges() {
+		log.Println("[INFO] Update function triggers...")
+		err = _applyTriggerPlan(client, params.FunctionName, triggerPlan)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.SmokeChecks != nil {
+		err = _runSmokeChecks(params)
+		if err != nil {
+			log.Printf("[WARN] %s", err)
+			rollbackErr := _rollbackFunction(client, params, previous, hasEnvironmentVariables)
+			if rollbackErr != nil {
+				return nil, fmt.Errorf("%s, and rollback failed: %s", err, rollbackErr)
+			}
+			return nil, fmt.Errorf("%s, rolled back to previous image and env", err)
+		}
+	}
 	response, err := _getFunctionInfo(client, params)
 	if err != nil {
 		return nil, err
 	}
+	_maskSecretEnv(response, params.EnvUpdate)
 	return response, nil
 }
+
+func _runSmokeChecks(params DeployParams) error {
+	log.Println("[INFO] Run smoke checks...")
+	return ezcommon.RunSmokeChecks(params.SmokeChecks,
+		func(payload string) (*ezcommon.InvokeResult, error) {
+			return Invoke(InvokeParams{
+				Region:       params.Region,
+				FunctionName: params.FunctionName,
+				Payload:      payload,
+				Mode:         ezcommon.INVOKE_MODE_SYNC,
+			})
+		})
+}
+
+/*
+Redeploy previous image, and restore previous env if it was updated.
+Triggers are not rolled back.
+*/
+func _rollbackFunction(
+	client *scf.Client,
+	params DeployParams,
+	previous *scf.GetFunctionResponse,
+	restoreEnv bool,
+) error {
+	imageConfig := previous.Response.ImageConfig
+	if imageConfig == nil || _strValue(imageConfig.ImageUri) == "" {
+		return fmt.Errorf("no previous image")
+	}
+	waitFunctionTimeout := time.Duration(180 * time.Second)
+	log.Printf("[INFO] Rollback ContainerImage=%s", *imageConfig.ImageUri)
+	_, err := _updateImageConfig(client, params, _getRollbackImageConfig(imageConfig))
+	if err != nil {
+		return err
+	}
+	err = _waitFunctionActive(client, params, waitFunctionTimeout)
+	if err != nil {
+		return err
+	}
+	if restoreEnv {
+		log.Println("[INFO] Rollback function env...")
+		_, err = _updateConfig(client, params, _getResponseEnv(previous))
+		if err != nil {
+			return err
+		}
+		return _waitFunctionActive(client, params, waitFunctionTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package tencent
+
+import (
+	"testing"
+
+	scf "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/scf/v20180416"
+)
+
+func TestGetRollbackImageConfig(t *testing.T) {
+	previous := &scf.ImageConfig{
+		ImageType: strRef(IMAGE_TYPE_PERSONAL),
+		ImageUri:  strRef("ccr.ccs.tencentyun.com/ns/app:v1"),
+		Command:   strRef("/app/start"),
+	}
+	config := _getRollbackImageConfig(previous)
+	if _strValue(config.ImageUri) != "ccr.ccs.tencentyun.com/ns/app:v1" ||
+		_strValue(config.Command) != "/app/start" {
+		t.Errorf("image or command not restored: %v", config)
+	}
+	// 之前未设置的值需要显式清空，否则会保留失败部署的值
+	for name, value := range map[string]*string{
+		"Args":       config.Args,
+		"EntryPoint": config.EntryPoint,
+	} {
+		if value == nil || *value != "" {
+			t.Errorf("%s = %v, expect explicit empty", name, value)
+		}
+	}
+	if config.ContainerImageAccelerate == nil || *config.ContainerImageAccelerate {
+		t.Errorf("ContainerImageAccelerate = %v, expect explicit false",
+			config.ContainerImageAccelerate)
+	}
+	isAccelerate := true
+	previous.ContainerImageAccelerate = &isAccelerate
+	config = _getRollbackImageConfig(previous)
+	if config.ContainerImageAccelerate == nil || !*config.ContainerImageAccelerate {
+		t.Error("ContainerImageAccelerate not restored")
+	}
+	if previous.Args != nil {
+		t.Error("previous config modified")
+	}
+}
+
+func TestGetImageConfig(t *testing.T) {
+	var currentPort int64 = 9000
+	var newPort int64 = 8080
+	current := &scf.ImageConfig{
+		ImagePort: &currentPort,
+		Command:   strRef("/app/start"),
+		Args:      strRef("worker"),
+	}
+	cases := []struct {
+		name    string
+		params  DeployParams
+		port    int64
+		command string
+		args    string
+	}{
+		{"unchanged", DeployParams{}, currentPort, "/app/start", "worker"},
+		{"port", DeployParams{ImagePort: &newPort}, newPort, "/app/start", "worker"},
+		{"args", DeployParams{Args: "web"}, currentPort, "/app/start", "web"},
+	}
+	for _, c := range cases {
+		config := _getImageConfig(current, c.params, "ccr.ccs.tencentyun.com/ns/app:v2")
+		if config.ImagePort == nil || *config.ImagePort != c.port {
+			t.Errorf("%s: ImagePort = %v, expect %d", c.name, config.ImagePort, c.port)
+		}
+		if _strValue(config.Command) != c.command || _strValue(config.Args) != c.args {
+			t.Errorf("%s: command = %q args = %q, expect %q %q", c.name,
+				_strValue(config.Command), _strValue(config.Args), c.command, c.args)
+		}
+	}
+}
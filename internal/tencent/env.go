@@ -0,0 +1,16 @@
+package tencent
+
+import (
+	"regexp"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+)
+
+// https://cloud.tencent.com/document/product/583/30228
+var EnvRules = ezcommon.EnvRules{
+	Platform:         "tencent scf",
+	NameRegex:        regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`),
+	NameDescription:  "must start with letter and contain only letters, digits and _",
+	ReservedPrefixes: []string{"SCF_", "TENCENTCLOUD_", "QCLOUD_"},
+	MaxTotalSize:     4 * 1024,
+}
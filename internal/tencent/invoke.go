@@ -0,0 +1,166 @@
+package tencent
+
+import (
+	"fmt"
+	"log"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	scf "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/scf/v20180416"
+)
+
+/*
+Payload templates of SCF trigger events.
+https://cloud.tencent.com/document/product/583/9705
+*/
+var PayloadTemplates = ezcommon.PayloadTemplates{
+	ezcommon.PAYLOAD_TEMPLATE_TIMER: `{
+    "Type": "Timer",
+    "TriggerName": "EveryDay",
+    "Time": "2024-01-01T00:00:00Z",
+    "Message": ""
+}`,
+	ezcommon.PAYLOAD_TEMPLATE_APIGW: `{
+    "requestContext": {
+        "serviceId": "service-local",
+        "path": "/",
+        "httpMethod": "GET",
+        "requestId": "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+        "identity": {},
+        "sourceIp": "127.0.0.1",
+        "stage": "release"
+    },
+    "headers": {
+        "accept": "*/*",
+        "host": "service-local.gz.apigw.tencentcs.com",
+        "user-agent": "ezfaas"
+    },
+    "body": "",
+    "isBase64Encoded": false,
+    "pathParameters": {},
+    "queryStringParameters": {},
+    "headerParameters": {},
+    "stageVariables": {"stage": "release"},
+    "path": "/",
+    "queryString": {},
+    "httpMethod": "GET"
+}`,
+	ezcommon.PAYLOAD_TEMPLATE_COS: `{
+    "Records": [{
+        "cos": {
+            "cosSchemaVersion": "1.0",
+            "cosObject": {
+                "url": "http://example-1250000000.cos.ap-guangzhou.myqcloud.com/testfile",
+                "meta": {"x-cos-request-id": "NWMxOWY4MGFfMjViMjU4NjRfMTUyMV8yNzhhZjM=", "Content-Type": ""},
+                "vid": "",
+                "key": "/1250000000/example/testfile",
+                "size": 1029
+            },
+            "cosBucket": {"region": "gz", "name": "example", "appid": "1250000000"},
+            "cosNotificationId": "unkown"
+        },
+        "event": {
+            "eventName": "cos:ObjectCreated:*",
+            "eventVersion": "1.0",
+            "eventTime": 1704067200,
+            "eventSource": "qcs::cos",
+            "requestParameters": {"requestSourceIP": "127.0.0.1", "requestHeaders": {}},
+            "eventQueue": "qcs:0:cos:gz:1250000000:cos",
+            "reservedInfo": "",
+            "reqid": 179398952
+        }
+    }]
+}`,
+}
+
+type InvokeParams struct {
+	Region       string
+	FunctionName string
+	Qualifier    string // 为空表示$LATEST
+	Payload      string
+	Mode         string // sync 或 async
+}
+
+func _newSCFClient(region string) (*scf.Client, error) {
+	provider := common.DefaultProfileProvider()
+	credentail, err := provider.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	clientProfile := profile.NewClientProfile()
+	return scf.NewClient(credentail, region, clientProfile)
+}
+
+func _getFunctionMemorySize(client *scf.Client, functionName string, qualifier string) (int64, error) {
+	request := scf.NewGetFunctionRequest()
+	request.FunctionName = &functionName
+	if qualifier != "" {
+		request.Qualifier = &qualifier
+	}
+	response, err := client.GetFunction(request)
+	if err != nil {
+		return 0, err
+	}
+	return _int64Value(response.Response.MemorySize), nil
+}
+
+/*
+Invoke function by SCF Invoke API, log tail is returned only in sync mode.
+https://cloud.tencent.com/document/product/583/17243
+*/
+func Invoke(params InvokeParams) (*ezcommon.InvokeResult, error) {
+	client, err := _newSCFClient(params.Region)
+	if err != nil {
+		return nil, err
+	}
+	request := scf.NewInvokeRequest()
+	request.FunctionName = &params.FunctionName
+	request.ClientContext = &params.Payload
+	if params.Qualifier != "" {
+		request.Qualifier = &params.Qualifier
+	}
+	if params.Mode == ezcommon.INVOKE_MODE_ASYNC {
+		request.InvocationType = strRef("Event")
+	} else {
+		request.InvocationType = strRef("RequestResponse")
+		request.LogType = strRef("Tail")
+	}
+	log.Printf("[INFO] Invoke Function=%s Qualifier=%s Mode=%s",
+		params.FunctionName, params.Qualifier, params.Mode)
+	response, err := client.Invoke(request)
+	if err != nil {
+		return nil, err
+	}
+	output := response.Response.Result
+	if output == nil {
+		return nil, fmt.Errorf("invoke result is empty, RequestId=%s",
+			_strValue(response.Response.RequestId))
+	}
+	result := ezcommon.InvokeResult{
+		RequestId:      _strValue(output.FunctionRequestId),
+		Body:           _strValue(output.RetMsg),
+		Duration:       _float64Value(output.Duration),
+		BilledDuration: _int64Value(output.BillDuration),
+		MemUsage:       float64(_int64Value(output.MemUsage)) / 1024 / 1024,
+		Log:            _strValue(output.Log),
+	}
+	if _int64Value(output.InvokeResult) != 0 {
+		result.Error = _strValue(output.ErrMsg)
+	}
+	if params.Mode != ezcommon.INVOKE_MODE_ASYNC {
+		result.MemorySize, err = _getFunctionMemorySize(
+			client, params.FunctionName, params.Qualifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+func _float64Value(x *float64) float64 {
+	if x == nil {
+		return 0
+	}
+	return *x
+}
@@ -0,0 +1,148 @@
+package tencent
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	scf "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/scf/v20180416"
+)
+
+// 请求状态码，执行中为1，执行完成后为函数的返回码，0表示成功
+const REQUEST_RETCODE_RUNNING int64 = 1
+
+type JobRunParams struct {
+	Region       string
+	FunctionName string
+	Qualifier    string
+	Payload      string
+	Timeout      time.Duration // 0表示使用函数超时时间
+}
+
+type JobResult struct {
+	RequestId string
+	RetCode   int64
+	RetMsg    string
+	Duration  float64 // 毫秒
+}
+
+func (r *JobResult) IsSuccess() bool {
+	return r.RetCode == 0
+}
+
+/*
+Get status of invocation, nil if the request is not recorded yet.
+https://cloud.tencent.com/document/product/583/65348
+*/
+func _getRequestStatus(
+	client *scf.Client,
+	params JobRunParams,
+	requestId string,
+) (*scf.RequestStatus, error) {
+	request := scf.NewGetRequestStatusRequest()
+	request.FunctionName = &params.FunctionName
+	request.FunctionRequestId = &requestId
+	response, err := client.GetRequestStatus(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Response.Data) <= 0 {
+		return nil, nil
+	}
+	return response.Response.Data[0], nil
+}
+
+func _isRequestFinished(status *scf.RequestStatus) bool {
+	return _int64Value(status.RetCode) != REQUEST_RETCODE_RUNNING ||
+		_float64Value(status.Duration) > 0
+}
+
+func _getJobTimeout(client *scf.Client, params JobRunParams) (time.Duration, error) {
+	if params.Timeout > 0 {
+		return params.Timeout, nil
+	}
+	request := scf.NewGetFunctionRequest()
+	request.FunctionName = &params.FunctionName
+	if params.Qualifier != "" {
+		request.Qualifier = &params.Qualifier
+	}
+	response, err := client.GetFunction(request)
+	if err != nil {
+		return 0, err
+	}
+	// 预留排队和启动实例的时间
+	seconds := _int64Value(response.Response.Timeout)
+	return time.Duration(seconds)*time.Second + 3*time.Minute, nil
+}
+
+/*
+Run job function by async invocation, wait until it finished and print logs
+of the invocation.
+*/
+func RunJob(params JobRunParams) (*JobResult, error) {
+	client, err := _newSCFClient(params.Region)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := _getJobTimeout(client, params)
+	if err != nil {
+		return nil, err
+	}
+	begin := time.Now()
+	invokeResult, err := Invoke(InvokeParams{
+		Region:       params.Region,
+		FunctionName: params.FunctionName,
+		Qualifier:    params.Qualifier,
+		Payload:      params.Payload,
+		Mode:         ezcommon.INVOKE_MODE_ASYNC,
+	})
+	if err != nil {
+		return nil, err
+	}
+	requestId := invokeResult.RequestId
+	log.Printf("[INFO] Job started RequestId=%s", requestId)
+	fetch, err := GetLogFetcher(LogsParams{
+		Region:       params.Region,
+		FunctionName: params.FunctionName,
+		Qualifier:    params.Qualifier,
+		RequestId:    requestId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 每次查询返回该请求到目前为止的全部日志，只输出新增部分
+	tail := ezcommon.NewRequestLogTail()
+	logStart := begin.Add(-time.Minute)
+	deadline := begin.Add(timeout)
+	i := 1
+	for {
+		status, err := _getRequestStatus(client, params, requestId)
+		if err != nil {
+			return nil, err
+		}
+		isFinished := status != nil && _isRequestFinished(status)
+		logs, err := fetch(logStart, time.Now())
+		if err != nil {
+			log.Printf("[WARN] Fetch logs: %s", err)
+		}
+		tail.Print(logs, isFinished)
+		if isFinished {
+			return &JobResult{
+				RequestId: requestId,
+				RetCode:   _int64Value(status.RetCode),
+				RetMsg:    _strValue(status.RetMsg),
+				Duration:  _float64Value(status.Duration),
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("job not finished in %s, RequestId=%s", timeout, requestId)
+		}
+		if i%6 == 0 {
+			log.Printf("[INFO] Wait job finished, elapsed=%s",
+				time.Since(begin).Round(time.Second))
+		}
+		time.Sleep(time.Duration(5 * time.Second))
+		i += 1
+	}
+}
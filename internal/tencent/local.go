@@ -0,0 +1,84 @@
+package tencent
+
+import (
+	"fmt"
+	"net/http"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+)
+
+// 镜像函数固定监听9000端口
+const SCF_IMAGE_PORT int = 9000
+
+/*
+Runtime conventions of SCF image function, event function is invoked by
+POST /event-invoke, web function receives http requests directly.
+https://cloud.tencent.com/document/product/583/56051
+*/
+func GetLocalRuntime(config ezcommon.LocalRunConfig) ezcommon.LocalRuntime {
+	port := config.Port
+	if port <= 0 {
+		port = SCF_IMAGE_PORT
+	}
+	memorySize := fmt.Sprintf("%d", config.MemorySize)
+	timeout := fmt.Sprintf("%d", config.Timeout)
+	return ezcommon.LocalRuntime{
+		Platform: "tencent",
+		Env: map[string]string{
+			"SCF_FUNCTIONNAME":    config.FunctionName,
+			"SCF_FUNCTIONVERSION": "$LATEST",
+			"SCF_NAMESPACE":       "default",
+			"SCF_MEMORY_SIZE":     memorySize,
+			"SCF_TIMEOUT":         timeout,
+			"SCF_RUNTIME":         "CustomImage",
+			"TENCENTCLOUD_REGION": config.Region,
+			"TENCENTCLOUD_RUNENV": "SCF",
+		},
+		ContainerPort: port,
+		EventPath:     "/event-invoke",
+		EventHeaders: map[string]string{
+			"X-Scf-Name":      config.FunctionName,
+			"X-Scf-Namespace": "default",
+			"X-Scf-Version":   "$LATEST",
+			"X-Scf-Region":    config.Region,
+			"X-Scf-Memory":    memorySize,
+			"X-Scf-Timeout":   timeout,
+		},
+		RequestIdHeader:  "X-Scf-Request-Id",
+		HTTPEventEncoder: EncodeAPIGatewayEvent,
+	}
+}
+
+/*
+Convert http request to API Gateway integrated request event, headers are
+lower cased the same as API Gateway.
+https://cloud.tencent.com/document/product/583/12513
+*/
+func EncodeAPIGatewayEvent(
+	request *http.Request,
+	body []byte,
+	requestId string,
+) map[string]interface{} {
+	eventBody, isBase64Encoded := ezcommon.EncodeEventBody(body)
+	return map[string]interface{}{
+		"requestContext": map[string]interface{}{
+			"serviceId":  "service-local",
+			"path":       request.URL.Path,
+			"httpMethod": request.Method,
+			"requestId":  requestId,
+			"identity":   map[string]interface{}{},
+			"sourceIp":   ezcommon.GetSourceIp(request),
+			"stage":      "release",
+		},
+		"headers":               ezcommon.JoinHeaderValues(request.Header, true),
+		"body":                  eventBody,
+		"isBase64Encoded":       isBase64Encoded,
+		"pathParameters":        map[string]string{},
+		"queryStringParameters": map[string]string{},
+		"headerParameters":      map[string]string{},
+		"stageVariables":        map[string]string{"stage": "release"},
+		"path":                  request.URL.Path,
+		"queryString":           ezcommon.FirstQueryValues(request.URL.Query()),
+		"httpMethod":            request.Method,
+	}
+}
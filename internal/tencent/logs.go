@@ -0,0 +1,107 @@
+package tencent
+
+import (
+	"fmt"
+	"time"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	scf "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/scf/v20180416"
+)
+
+// 云函数接口的时间为北京时间
+var _beijingTime = time.FixedZone("CST", 8*3600)
+
+const _scfTimeLayout string = "2006-01-02 15:04:05"
+
+type LogsParams struct {
+	Region       string
+	FunctionName string
+	Qualifier    string // 为空表示所有版本
+	RequestId    string // 为空表示所有请求
+}
+
+/*
+Last modified time of function, both code and config updates change it.
+Old instances may still output logs after it, so it is not a version filter.
+*/
+func GetLastModifiedTime(params LogsParams) (time.Time, error) {
+	client, err := _newSCFClient(params.Region)
+	if err != nil {
+		return time.Time{}, err
+	}
+	request := scf.NewGetFunctionRequest()
+	request.FunctionName = &params.FunctionName
+	if params.Qualifier != "" {
+		request.Qualifier = &params.Qualifier
+	}
+	response, err := client.GetFunction(request)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(_scfTimeLayout, _strValue(response.Response.ModTime), _beijingTime)
+}
+
+/*
+Get log fetcher by SCF GetFunctionLogs, each log is output of one invocation
+so far, it may grow until the invocation finished.
+https://cloud.tencent.com/document/product/583/18583
+*/
+func GetLogFetcher(params LogsParams) (ezcommon.LogFetcher, error) {
+	client, err := _newSCFClient(params.Region)
+	if err != nil {
+		return nil, err
+	}
+	fetch := func(start time.Time, end time.Time) ([]ezcommon.FunctionLog, error) {
+		var logs []ezcommon.FunctionLog
+		var limit int64 = 100
+		var offset int64 = 0
+		for {
+			request := scf.NewGetFunctionLogsRequest()
+			request.FunctionName = &params.FunctionName
+			if params.Qualifier != "" {
+				request.Qualifier = &params.Qualifier
+			}
+			if params.RequestId != "" {
+				request.FunctionRequestId = &params.RequestId
+			}
+			request.StartTime = strRef(start.In(_beijingTime).Format(_scfTimeLayout))
+			request.EndTime = strRef(end.In(_beijingTime).Format(_scfTimeLayout))
+			request.Order = strRef("asc")
+			request.OrderBy = strRef("start_time")
+			request.Offset = &offset
+			request.Limit = &limit
+			response, err := client.GetFunctionLogs(request)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range response.Response.Data {
+				startTime, err := time.ParseInLocation(
+					_scfTimeLayout, _strValue(item.StartTime), _beijingTime)
+				if err != nil {
+					return nil, fmt.Errorf("invalid log time: %s", err)
+				}
+				message := _strValue(item.Log)
+				if message == "" {
+					message = _strValue(item.RetMsg)
+				}
+				retCode := _int64Value(item.RetCode)
+				isRunning := retCode == REQUEST_RETCODE_RUNNING
+				logs = append(logs, ezcommon.FunctionLog{
+					Time:            startTime,
+					RequestId:       _strValue(item.RequestId),
+					Message:         message,
+					IsError:         retCode != 0 && !isRunning,
+					IsRequestOutput: true,
+					IsRunning:       isRunning,
+				})
+			}
+			offset += int64(len(response.Response.Data))
+			if int64(len(response.Response.Data)) < limit ||
+				offset >= _int64Value(response.Response.TotalCount) {
+				break
+			}
+		}
+		return logs, nil
+	}
+	return fetch, nil
+}
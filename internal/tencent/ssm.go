@@ -0,0 +1,71 @@
+package tencent
+
+import (
+	"fmt"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+)
+
+/* Resolve ssm://name#key reference from Tencent SSM, version is latest if not specified */
+type SSMSecretResolver struct {
+	Region string
+}
+
+func (r SSMSecretResolver) _getLatestVersion(region string, name string) (string, error) {
+	var result struct {
+		Response struct {
+			Versions []struct {
+				VersionId  string
+				CreateTime uint64
+			}
+		}
+	}
+	err := _callApi(region, "ssm", "2019-09-23", "ListSecretVersionIds",
+		map[string]interface{}{"SecretName": name}, &result)
+	if err != nil {
+		return "", err
+	}
+	var versionId string
+	var createTime uint64
+	for _, version := range result.Response.Versions {
+		if version.CreateTime >= createTime {
+			versionId, createTime = version.VersionId, version.CreateTime
+		}
+	}
+	if versionId == "" {
+		return "", fmt.Errorf("secret %s has no version", name)
+	}
+	return versionId, nil
+}
+
+func (r SSMSecretResolver) ResolveSecret(ref ezcommon.SecretRef) (string, error) {
+	region := ref.Query.Get("region")
+	if region == "" {
+		region = r.Region
+	}
+	if region == "" {
+		return "", fmt.Errorf("region of %s is required", ref.String())
+	}
+	versionId := ref.Query.Get("version")
+	if versionId == "" {
+		var err error
+		versionId, err = r._getLatestVersion(region, ref.Name)
+		if err != nil {
+			return "", err
+		}
+	}
+	var result struct {
+		Response struct {
+			SecretString string
+		}
+	}
+	err := _callApi(region, "ssm", "2019-09-23", "GetSecretValue",
+		map[string]interface{}{
+			"SecretName": ref.Name,
+			"VersionId":  versionId,
+		}, &result)
+	if err != nil {
+		return "", err
+	}
+	return ezcommon.GetSecretKeyValue(ref, result.Response.SecretString)
+}
@@ -11,20 +11,39 @@ import (
 	tcr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/tcr/v20190924"
 )
 
+const (
+	IMAGE_TYPE_PERSONAL   string = "personal"
+	IMAGE_TYPE_ENTERPRISE string = "enterprise"
+)
+
 type WaitDockerImageParams struct {
 	Region     string
 	Repository string
 	BuildId    string
+	ImageType  string // 为空表示个人版
+	RegistryId string // 企业版实例ID
 	Timeout    time.Duration
 }
 
+/*
+Extract repository name without registry host, the name is namespace and the
+full remaining path, eg: host/ns/group/app is ns/group/app.
+*/
 func extractRepoName(repository string) (string, error) {
 	parts := strings.Split(repository, "/")
+	// 第一段包含点号或端口时是镜像仓库域名
+	if len(parts) > 2 || strings.ContainsAny(parts[0], ".:") {
+		parts = parts[1:]
+	}
 	if len(parts) < 2 {
 		return "", fmt.Errorf("invalid repository name")
 	}
-	parts = parts[len(parts)-2:]
-	return parts[0] + "/" + parts[1], nil
+	for _, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("invalid repository name")
+		}
+	}
+	return strings.Join(parts, "/"), nil
 }
 
 func queryImageTagReady(client *tcr.Client, repoName string, tag string) (bool, error) {
@@ -41,6 +60,37 @@ func queryImageTagReady(client *tcr.Client, repoName string, tag string) (bool,
 	return isReady, nil
 }
 
+/*
+Query image of TCR enterprise instance, repository is namespace/name.
+https://cloud.tencent.com/document/product/1141/41811
+*/
+func queryEnterpriseImageTagReady(
+	client *tcr.Client,
+	registryId string,
+	repoName string,
+	tag string,
+) (bool, error) {
+	parts := strings.SplitN(repoName, "/", 2)
+	request := tcr.NewDescribeImagesRequest()
+	request.RegistryId = strRef(registryId)
+	request.NamespaceName = strRef(parts[0])
+	request.RepositoryName = strRef(parts[1])
+	request.ImageVersion = strRef(tag)
+	request.Limit = int64Ref(100)
+	request.Offset = int64Ref(0)
+	response, err := client.DescribeImages(request)
+	if err != nil {
+		return false, err
+	}
+	// 镜像版本参数是模糊匹配
+	for _, image := range response.Response.ImageInfoList {
+		if _strValue(image.ImageVersion) == tag {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func WaitDockerImageReady(params WaitDockerImageParams) error {
 	repoName, err := extractRepoName(params.Repository)
 	if err != nil {
@@ -59,7 +109,13 @@ func WaitDockerImageReady(params WaitDockerImageParams) error {
 	deadline := time.Now().Add(params.Timeout)
 	i := 1
 	for {
-		isReady, err := queryImageTagReady(client, repoName, params.BuildId)
+		var isReady bool
+		if params.ImageType == IMAGE_TYPE_ENTERPRISE {
+			isReady, err = queryEnterpriseImageTagReady(
+				client, params.RegistryId, repoName, params.BuildId)
+		} else {
+			isReady, err = queryImageTagReady(client, repoName, params.BuildId)
+		}
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,30 @@
+package tencent
+
+import "testing"
+
+func TestExtractRepoName(t *testing.T) {
+	cases := []struct {
+		repository string
+		expect     string // 为空表示无效
+	}{
+		{"ccr.ccs.tencentyun.com/ns/app", "ns/app"},
+		{"demo.tencentcloudcr.com/ns/group/app", "ns/group/app"},
+		{"localhost:5000/ns/app", "ns/app"},
+		{"ns/app", "ns/app"},
+		{"ccr.ccs.tencentyun.com/app", ""},
+		{"app", ""},
+		{"ccr.ccs.tencentyun.com/ns//app", ""},
+	}
+	for _, c := range cases {
+		repoName, err := extractRepoName(c.repository)
+		if c.expect == "" {
+			if err == nil {
+				t.Errorf("%s: expect error, got %q", c.repository, repoName)
+			}
+			continue
+		}
+		if err != nil || repoName != c.expect {
+			t.Errorf("%s: repo name = %q err = %v, expect %q", c.repository, repoName, err, c.expect)
+		}
+	}
+}
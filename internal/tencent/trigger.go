@@ -0,0 +1,319 @@
+package tencent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	scf "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/scf/v20180416"
+)
+
+const (
+	TRIGGER_ENABLE_OPEN  string = "OPEN"
+	TRIGGER_ENABLE_CLOSE string = "CLOSE"
+)
+
+type TriggerPlan struct {
+	Create       []*scf.CreateTriggerRequest
+	Delete       []*scf.TriggerInfo
+	UpdateStatus []*scf.UpdateTriggerStatusRequest
+	Unmanaged    []*scf.TriggerInfo // 未声明且不由ezfaas管理，保留不变
+}
+
+func (p *TriggerPlan) HasChanges() bool {
+	return len(p.Create) > 0 || len(p.Delete) > 0 || len(p.UpdateStatus) > 0
+}
+
+func (p *TriggerPlan) Changes() []ezcommon.TriggerChange {
+	var changes []ezcommon.TriggerChange
+	for _, trigger := range p.Delete {
+		changes = append(changes, ezcommon.TriggerChange{
+			Action: ezcommon.TRIGGER_ACTION_DELETE,
+			Key:    _getTriggerInfoKey(trigger),
+		})
+	}
+	for _, request := range p.Create {
+		changes = append(changes, ezcommon.TriggerChange{
+			Action: ezcommon.TRIGGER_ACTION_CREATE,
+			Key:    _getTriggerKey(*request.Type, *request.TriggerName, *request.Qualifier),
+			Detail: fmt.Sprintf("enable=%s desc=%s", *request.Enable, _strValue(request.TriggerDesc)),
+		})
+	}
+	for _, request := range p.UpdateStatus {
+		changes = append(changes, ezcommon.TriggerChange{
+			Action: ezcommon.TRIGGER_ACTION_UPDATE,
+			Key:    _getTriggerKey(*request.Type, *request.TriggerName, *request.Qualifier),
+			Detail: fmt.Sprintf("enable=%s", *request.Enable),
+		})
+	}
+	for _, trigger := range p.Unmanaged {
+		changes = append(changes, ezcommon.TriggerChange{
+			Action: ezcommon.TRIGGER_ACTION_KEEP,
+			Key:    _getTriggerInfoKey(trigger),
+		})
+	}
+	return changes
+}
+
+func _toJSONString(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func _boolString(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+/* Get trigger name and desc by trigger type */
+func _getTriggerDesc(spec ezcommon.TriggerSpec) (string, string, error) {
+	name := spec.Name
+	var desc string
+	switch spec.Type {
+	case ezcommon.TRIGGER_TIMER:
+		if spec.Cron == "" && spec.Desc == "" {
+			return "", "", fmt.Errorf("cron is required")
+		}
+		desc = spec.Cron
+	case ezcommon.TRIGGER_APIGW:
+		if spec.Service == "" && spec.Desc == "" {
+			return "", "", fmt.Errorf("service is required")
+		}
+		method := spec.Method
+		if method == "" {
+			method = "ANY"
+		}
+		environment := spec.Environment
+		if environment == "" {
+			environment = "release"
+		}
+		desc = _toJSONString(map[string]interface{}{
+			"api": map[string]interface{}{
+				"authRequired":         _boolString(spec.AuthRequired),
+				"requestConfig":        map[string]string{"method": method},
+				"isIntegratedResponse": "TRUE",
+			},
+			"service": map[string]string{"serviceId": spec.Service},
+			"release": map[string]string{"environmentName": environment},
+		})
+	case ezcommon.TRIGGER_HTTP:
+		authType := spec.AuthType
+		if authType == "" {
+			authType = "NONE"
+		}
+		desc = _toJSONString(map[string]interface{}{
+			"AuthType": authType,
+			"NetConfig": map[string]bool{
+				"EnableIntranet": spec.Intranet,
+				"EnableExtranet": true,
+			},
+		})
+	case ezcommon.TRIGGER_COS:
+		if spec.Bucket == "" {
+			return "", "", fmt.Errorf("bucket is required")
+		}
+		name = spec.Bucket
+		event := spec.Event
+		if event == "" {
+			event = "cos:ObjectCreated:*"
+		}
+		desc = _toJSONString(map[string]interface{}{
+			"event": event,
+			"filter": map[string]string{
+				"Prefix": spec.Prefix,
+				"Suffix": spec.Suffix,
+			},
+		})
+	case ezcommon.TRIGGER_CMQ:
+		if spec.Topic == "" {
+			return "", "", fmt.Errorf("topic is required")
+		}
+		name = spec.Topic
+	default:
+		return "", "", fmt.Errorf("invalid trigger type %q", spec.Type)
+	}
+	if spec.Desc != "" {
+		desc = spec.Desc
+	}
+	return name, desc, nil
+}
+
+func _getTriggerKey(triggerType string, name string, qualifier string) string {
+	return fmt.Sprintf("%s/%s@%s", triggerType, name, qualifier)
+}
+
+func _getTriggerInfoKey(trigger *scf.TriggerInfo) string {
+	return _getTriggerKey(
+		_strValue(trigger.Type), _strValue(trigger.TriggerName), _strValue(trigger.Qualifier))
+}
+
+/* Desc of current trigger in the format of create request, desc of timer is listed as JSON */
+func _getTriggerInfoDesc(trigger *scf.TriggerInfo) string {
+	desc := _strValue(trigger.TriggerDesc)
+	if _strValue(trigger.Type) == ezcommon.TRIGGER_TIMER {
+		var timer struct {
+			Cron string `json:"cron"`
+		}
+		if json.Unmarshal([]byte(desc), &timer) == nil && timer.Cron != "" {
+			return timer.Cron
+		}
+	}
+	return desc
+}
+
+func _listTriggers(client *scf.Client, functionName string) ([]*scf.TriggerInfo, error) {
+	var triggers []*scf.TriggerInfo
+	var limit uint64 = 100
+	var offset uint64 = 0
+	for {
+		request := scf.NewListTriggersRequest()
+		request.FunctionName = &functionName
+		request.Offset = uint64Ref(offset)
+		request.Limit = uint64Ref(limit)
+		response, err := client.ListTriggers(request)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, response.Response.Triggers...)
+		offset += uint64(len(response.Response.Triggers))
+		if len(response.Response.Triggers) <= 0 || offset >= *response.Response.TotalCount {
+			break
+		}
+	}
+	return triggers, nil
+}
+
+/*
+Plan changes to make triggers same as declared. Triggers are recreated if
+config changed or not created by ezfaas, so they are managed afterwards.
+Undeclared triggers not managed by ezfaas are deleted only if prune.
+*/
+func _planTriggers(
+	client *scf.Client,
+	functionName string,
+	config *ezcommon.TriggersConfig,
+	prune bool,
+) (*TriggerPlan, error) {
+	current, err := _listTriggers(client, functionName)
+	if err != nil {
+		return nil, err
+	}
+	return _diffTriggers(functionName, current, config, prune)
+}
+
+func _diffTriggers(
+	functionName string,
+	current []*scf.TriggerInfo,
+	config *ezcommon.TriggersConfig,
+	prune bool,
+) (*TriggerPlan, error) {
+	currentMap := map[string]*scf.TriggerInfo{}
+	for _, trigger := range current {
+		currentMap[_getTriggerInfoKey(trigger)] = trigger
+	}
+	var plan TriggerPlan
+	var violations []string
+	declared := map[string]bool{}
+	for _, spec := range config.Triggers {
+		name, desc, err := _getTriggerDesc(spec)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %s", spec.Name, err))
+			continue
+		}
+		qualifier := spec.Qualifier
+		if qualifier == "" {
+			qualifier = "$DEFAULT"
+		}
+		enable := TRIGGER_ENABLE_OPEN
+		if !spec.IsEnabled() {
+			enable = TRIGGER_ENABLE_CLOSE
+		}
+		key := _getTriggerKey(spec.Type, name, qualifier)
+		declared[key] = true
+		request := scf.NewCreateTriggerRequest()
+		request.FunctionName = strRef(functionName)
+		request.TriggerName = strRef(name)
+		request.Type = strRef(spec.Type)
+		request.Qualifier = strRef(qualifier)
+		request.Enable = strRef(enable)
+		request.Description = strRef(ezcommon.TRIGGER_DESCRIPTION)
+		if desc != "" {
+			request.TriggerDesc = strRef(desc)
+		}
+		if spec.Payload != "" {
+			request.CustomArgument = strRef(spec.Payload)
+		}
+		trigger, ok := currentMap[key]
+		if !ok {
+			plan.Create = append(plan.Create, request)
+			continue
+		}
+		if _strValue(trigger.Description) != ezcommon.TRIGGER_DESCRIPTION ||
+			!ezcommon.IsJSONSubset(desc, _getTriggerInfoDesc(trigger)) ||
+			spec.Payload != _strValue(trigger.CustomArgument) {
+			plan.Delete = append(plan.Delete, trigger)
+			plan.Create = append(plan.Create, request)
+			continue
+		}
+		isEnabled := trigger.Enable != nil && *trigger.Enable == 1
+		if isEnabled != spec.IsEnabled() {
+			statusRequest := scf.NewUpdateTriggerStatusRequest()
+			statusRequest.FunctionName = strRef(functionName)
+			statusRequest.TriggerName = trigger.TriggerName
+			statusRequest.Type = trigger.Type
+			statusRequest.Qualifier = trigger.Qualifier
+			statusRequest.TriggerDesc = trigger.TriggerDesc
+			statusRequest.Enable = strRef(enable)
+			plan.UpdateStatus = append(plan.UpdateStatus, statusRequest)
+		}
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("invalid triggers:\n  - %s", strings.Join(violations, "\n  - "))
+	}
+	for _, trigger := range current {
+		if declared[_getTriggerInfoKey(trigger)] {
+			continue
+		}
+		if ezcommon.IsTriggerRemovable(_strValue(trigger.Description), prune) {
+			plan.Delete = append(plan.Delete, trigger)
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, trigger)
+		}
+	}
+	return &plan, nil
+}
+
+func _logTriggerPlan(plan *TriggerPlan) {
+	ezcommon.LogTriggerChanges(plan.Changes())
+}
+
+func _applyTriggerPlan(client *scf.Client, functionName string, plan *TriggerPlan) error {
+	for _, trigger := range plan.Delete {
+		request := scf.NewDeleteTriggerRequest()
+		request.FunctionName = strRef(functionName)
+		request.TriggerName = trigger.TriggerName
+		request.Type = trigger.Type
+		request.Qualifier = trigger.Qualifier
+		request.TriggerDesc = trigger.TriggerDesc
+		_, err := client.DeleteTrigger(request)
+		if err != nil {
+			return fmt.Errorf("delete trigger %s: %s", _getTriggerInfoKey(trigger), err)
+		}
+	}
+	for _, request := range plan.Create {
+		_, err := client.CreateTrigger(request)
+		if err != nil {
+			return fmt.Errorf("create trigger %s: %s", *request.TriggerName, err)
+		}
+	}
+	for _, request := range plan.UpdateStatus {
+		_, err := client.UpdateTriggerStatus(request)
+		if err != nil {
+			return fmt.Errorf("update trigger %s: %s", *request.TriggerName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package tencent
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	ezcommon "github.com/guyskk/ezfaas/internal/common"
+	scf "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/scf/v20180416"
+)
+
+// ListTriggers响应的触发器列表，定时触发器的描述是JSON格式
+const _testListTriggersResponse = `[
+	{
+		"Enable": 1,
+		"Qualifier": "$DEFAULT",
+		"TriggerName": "daily",
+		"Type": "timer",
+		"TriggerDesc": "{\"cron\":\"0 0 2 * * * *\"}",
+		"AvailableStatus": "Available",
+		"CustomArgument": "",
+		"AddTime": "2024-01-02 10:00:00",
+		"ModTime": "2024-01-02 10:00:00",
+		"ResourceId": "",
+		"BindStatus": "",
+		"TriggerAttribute": "",
+		"Description": "managed by ezfaas"
+	},
+	{
+		"Enable": 1,
+		"Qualifier": "$DEFAULT",
+		"TriggerName": "hourly",
+		"Type": "timer",
+		"TriggerDesc": "{\"cron\":\"0 0 * * * * *\"}",
+		"AvailableStatus": "Available",
+		"CustomArgument": "",
+		"AddTime": "2024-01-02 10:00:00",
+		"ModTime": "2024-01-02 10:00:00",
+		"ResourceId": "",
+		"BindStatus": "",
+		"TriggerAttribute": "",
+		"Description": ""
+	}
+]`
+
+func _loadTestTriggers(t *testing.T) []*scf.TriggerInfo {
+	var triggers []*scf.TriggerInfo
+	if err := json.Unmarshal([]byte(_testListTriggersResponse), &triggers); err != nil {
+		t.Fatal(err)
+	}
+	return triggers
+}
+
+func TestDiffTriggers(t *testing.T) {
+	disabled := false
+	daily := ezcommon.TriggerSpec{Name: "daily", Type: ezcommon.TRIGGER_TIMER, Cron: "0 0 2 * * * *"}
+	hourly := ezcommon.TriggerSpec{Name: "hourly", Type: ezcommon.TRIGGER_TIMER, Cron: "0 0 * * * * *"}
+	cases := []struct {
+		name   string
+		specs  []ezcommon.TriggerSpec
+		prune  bool
+		expect []string
+	}{
+		{
+			name:   "unchanged",
+			specs:  []ezcommon.TriggerSpec{daily},
+			expect: []string{"keep timer/hourly@$DEFAULT"},
+		},
+		{
+			name: "changed cron",
+			specs: []ezcommon.TriggerSpec{
+				{Name: "daily", Type: ezcommon.TRIGGER_TIMER, Cron: "0 0 3 * * * *"},
+			},
+			expect: []string{
+				"delete timer/daily@$DEFAULT",
+				"create timer/daily@$DEFAULT",
+				"keep timer/hourly@$DEFAULT",
+			},
+		},
+		{
+			name: "disable",
+			specs: []ezcommon.TriggerSpec{{
+				Name: "daily", Type: ezcommon.TRIGGER_TIMER, Cron: daily.Cron, Enabled: &disabled,
+			}},
+			expect: []string{"update timer/daily@$DEFAULT", "keep timer/hourly@$DEFAULT"},
+		},
+		{
+			name:   "undeclared managed",
+			specs:  nil,
+			expect: []string{"delete timer/daily@$DEFAULT", "keep timer/hourly@$DEFAULT"},
+		},
+		{
+			name:   "prune",
+			specs:  []ezcommon.TriggerSpec{daily},
+			prune:  true,
+			expect: []string{"delete timer/hourly@$DEFAULT"},
+		},
+		// 声明的触发器不是ezfaas创建的，重建后由ezfaas管理
+		{
+			name:   "declared unmanaged",
+			specs:  []ezcommon.TriggerSpec{daily, hourly},
+			expect: []string{"delete timer/hourly@$DEFAULT", "create timer/hourly@$DEFAULT"},
+		},
+		{
+			name:  "create",
+			specs: []ezcommon.TriggerSpec{daily, {Name: "url", Type: ezcommon.TRIGGER_HTTP}},
+			expect: []string{
+				"create http/url@$DEFAULT",
+				"keep timer/hourly@$DEFAULT",
+			},
+		},
+	}
+	for _, c := range cases {
+		config := &ezcommon.TriggersConfig{Triggers: c.specs}
+		plan, err := _diffTriggers("app", _loadTestTriggers(t), config, c.prune)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		summary := ezcommon.SummaryTriggerChanges(plan.Changes())
+		if !reflect.DeepEqual(summary, c.expect) {
+			t.Errorf("%s: plan = %q, expect %q", c.name, summary, c.expect)
+		}
+	}
+}
+
+func TestDiffTriggersInvalid(t *testing.T) {
+	specs := []ezcommon.TriggerSpec{{Name: "daily", Type: ezcommon.TRIGGER_TIMER}}
+	_, err := _diffTriggers("app", nil, &ezcommon.TriggersConfig{Triggers: specs}, false)
+	if err == nil {
+		t.Error("expect error for timer trigger without cron")
+	}
+}